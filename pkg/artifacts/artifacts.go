@@ -19,6 +19,34 @@ type Artifact struct {
 	Images      []string
 	Credentials []docker.Auth
 	RawResource map[string]interface{}
+	// Exposure is set on workloads by ClassifyExposure
+	Exposure *Exposure
+	// NetworkPolicyCoverage is set on workloads by AnalyzeNetworkPolicies
+	NetworkPolicyCoverage *NetworkPolicyCoverage
+	// HelmRelease is the release which rendered the artifact, set by MapHelmReleases
+	HelmRelease *ObjectRef
+	// GitOpsSource is set on artifacts managed by Argo CD or Flux by AttributeGitOpsSources
+	GitOpsSource *GitOpsSource
+	// ConfigReferences is set on workloads by AnalyzeConfigReferences
+	ConfigReferences []ConfigReference
+	// ImageHygiene is set on workloads by AnalyzeImageHygiene
+	ImageHygiene []ImageHygiene
+	// Cluster is the cluster the artifact was collected from
+	Cluster *ClusterIdentity
+	// VersionSkew is set on Cluster and NodeComponents artifacts by AnalyzeVersionSkew
+	VersionSkew []VersionSkew
+	// Support is the kubernetes version support status, set by AnalyzeVersionSkew
+	Support *SupportStatus
+}
+
+// ClusterIdentity identifies a scanned cluster
+type ClusterIdentity struct {
+	// ID is the stable cluster identifier, see k8s.Cluster GetClusterID
+	ID string
+	// Context is the kubeconfig context used to reach the cluster
+	Context string
+	// Name is the kubeconfig cluster name
+	Name string
 }
 
 // FromResource is a factory method to create an Artifact from an unstructured.Unstructured
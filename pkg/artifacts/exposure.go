@@ -0,0 +1,166 @@
+package artifacts
+
+import (
+	"slices"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Exposure levels, ordered from the least to the most exposed
+const (
+	// ExposureInternal workload is not selected by any service
+	ExposureInternal = "internal"
+	// ExposureCluster workload is reachable through a ClusterIP service
+	ExposureCluster = "cluster"
+	// ExposureNode workload is reachable on node addresses (NodePort, hostNetwork, hostPort)
+	ExposureNode = "node"
+	// ExposureExternal workload is reachable from outside the cluster (LoadBalancer, externalIPs, Ingress)
+	ExposureExternal = "external"
+)
+
+var exposureRank = map[string]int{
+	ExposureInternal: 0,
+	ExposureCluster:  1,
+	ExposureNode:     2,
+	ExposureExternal: 3,
+}
+
+// Exposure describes how reachable a workload is and which objects expose it
+type Exposure struct {
+	Level       string
+	HostNetwork bool
+	HostPorts   []int32
+	ExposedBy   []ObjectRef
+}
+
+func (e *Exposure) raise(level string) {
+	if exposureRank[level] > exposureRank[e.Level] {
+		e.Level = level
+	}
+}
+
+type service struct {
+	ref      ObjectRef
+	level    string
+	selector labels.Selector
+}
+
+// ClassifyExposure sets the Exposure of every workload artifact using the
+// Service and Ingress artifacts found in the same list
+func ClassifyExposure(artifactList []*Artifact) {
+	services := make(map[string][]service)
+	ingresses := make([]*Artifact, 0)
+	for _, a := range artifactList {
+		switch a.Kind {
+		case "Service":
+			if svc, ok := serviceFromArtifact(a); ok {
+				services[a.Namespace] = append(services[a.Namespace], svc)
+			}
+		case "Ingress":
+			ingresses = append(ingresses, a)
+		}
+	}
+	// services routed by an ingress are reachable from outside the cluster
+	ingressesByService := make(map[ObjectRef][]ObjectRef)
+	for _, ing := range ingresses {
+		for _, name := range ingressBackendServices(ing) {
+			svc := ObjectRef{Kind: "Service", Namespace: ing.Namespace, Name: name}
+			ingressesByService[svc] = append(ingressesByService[svc], ObjectRef{Kind: ing.Kind, Namespace: ing.Namespace, Name: ing.Name})
+		}
+	}
+
+	for _, a := range artifactList {
+		if !a.IsWorkload() {
+			continue
+		}
+		exposure := &Exposure{Level: ExposureInternal}
+		if spec := a.PodSpec(); spec != nil {
+			exposure.HostNetwork = spec.HostNetwork
+			for _, c := range append(spec.InitContainers, spec.Containers...) {
+				for _, p := range c.Ports {
+					if p.HostPort > 0 {
+						exposure.HostPorts = append(exposure.HostPorts, p.HostPort)
+					}
+				}
+			}
+			if exposure.HostNetwork || len(exposure.HostPorts) > 0 {
+				exposure.raise(ExposureNode)
+			}
+		}
+		podLabels := labels.Set(a.PodLabels())
+		for _, svc := range services[a.Namespace] {
+			if !svc.selector.Matches(podLabels) {
+				continue
+			}
+			exposure.raise(svc.level)
+			exposure.ExposedBy = append(exposure.ExposedBy, svc.ref)
+			for _, ing := range ingressesByService[svc.ref] {
+				exposure.raise(ExposureExternal)
+				exposure.ExposedBy = append(exposure.ExposedBy, ing)
+			}
+		}
+		a.Exposure = exposure
+	}
+}
+
+func serviceFromArtifact(a *Artifact) (service, bool) {
+	selector, _, err := unstructured.NestedStringMap(a.RawResource, "spec", "selector")
+	// services without selector are not backed by workload pods
+	if err != nil || len(selector) == 0 {
+		return service{}, false
+	}
+	svcType, _, _ := unstructured.NestedString(a.RawResource, "spec", "type")
+	externalIPs, _, _ := unstructured.NestedStringSlice(a.RawResource, "spec", "externalIPs")
+
+	level := ExposureCluster
+	switch {
+	case svcType == "LoadBalancer" || len(externalIPs) > 0:
+		level = ExposureExternal
+	case svcType == "NodePort":
+		level = ExposureNode
+	}
+	return service{
+		ref:      ObjectRef{Kind: a.Kind, Namespace: a.Namespace, Name: a.Name},
+		level:    level,
+		selector: labels.SelectorFromSet(selector),
+	}, true
+}
+
+// ingressBackendServices returns the names of the services an ingress routes to,
+// for both networking.k8s.io/v1 and legacy backends
+func ingressBackendServices(a *Artifact) []string {
+	names := make([]string, 0)
+	addBackend := func(backend map[string]interface{}) {
+		name, ok, _ := unstructured.NestedString(backend, "service", "name")
+		if !ok {
+			name, ok, _ = unstructured.NestedString(backend, "serviceName")
+		}
+		if ok && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	for _, key := range []string{"defaultBackend", "backend"} {
+		if backend, ok, _ := unstructured.NestedMap(a.RawResource, "spec", key); ok {
+			addBackend(backend)
+		}
+	}
+	rules, _, _ := unstructured.NestedSlice(a.RawResource, "spec", "rules")
+	for _, rule := range rules {
+		ruleMap, ok := rule.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		paths, _, _ := unstructured.NestedSlice(ruleMap, "http", "paths")
+		for _, path := range paths {
+			pathMap, ok := path.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if backend, ok, _ := unstructured.NestedMap(pathMap, "backend"); ok {
+				addBackend(backend)
+			}
+		}
+	}
+	return names
+}
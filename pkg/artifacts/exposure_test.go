@@ -0,0 +1,197 @@
+package artifacts
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+
+	"github.com/aquasecurity/trivy-kubernetes/pkg/k8s/docker"
+)
+
+func TestClassifyExposure(t *testing.T) {
+	deployment := `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: default
+spec:
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      containers:
+        - name: web
+          image: nginx:1.25
+`
+	tests := []struct {
+		name     string
+		objects  []string
+		expected *Exposure
+	}{
+		{
+			name:     "not selected by any service",
+			objects:  []string{deployment},
+			expected: &Exposure{Level: ExposureInternal},
+		},
+		{
+			name: "cluster ip service",
+			objects: []string{deployment, `
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: default
+spec:
+  selector:
+    app: web
+`},
+			expected: &Exposure{
+				Level:     ExposureCluster,
+				ExposedBy: []ObjectRef{{Kind: "Service", Namespace: "default", Name: "web"}},
+			},
+		},
+		{
+			name: "service in another namespace",
+			objects: []string{deployment, `
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: other
+spec:
+  type: LoadBalancer
+  selector:
+    app: web
+`},
+			expected: &Exposure{Level: ExposureInternal},
+		},
+		{
+			name: "node port service",
+			objects: []string{deployment, `
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: default
+spec:
+  type: NodePort
+  selector:
+    app: web
+`},
+			expected: &Exposure{
+				Level:     ExposureNode,
+				ExposedBy: []ObjectRef{{Kind: "Service", Namespace: "default", Name: "web"}},
+			},
+		},
+		{
+			name: "service with external ips",
+			objects: []string{deployment, `
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: default
+spec:
+  externalIPs:
+    - 1.2.3.4
+  selector:
+    app: web
+`},
+			expected: &Exposure{
+				Level:     ExposureExternal,
+				ExposedBy: []ObjectRef{{Kind: "Service", Namespace: "default", Name: "web"}},
+			},
+		},
+		{
+			name: "ingress backed by a selecting service",
+			objects: []string{deployment, `
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: default
+spec:
+  selector:
+    app: web
+`, `
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: web
+  namespace: default
+spec:
+  rules:
+    - http:
+        paths:
+          - path: /
+            pathType: Prefix
+            backend:
+              service:
+                name: web
+                port:
+                  number: 80
+          - path: /api
+            pathType: Prefix
+            backend:
+              service:
+                name: web
+                port:
+                  number: 80
+`},
+			expected: &Exposure{
+				Level: ExposureExternal,
+				ExposedBy: []ObjectRef{
+					{Kind: "Service", Namespace: "default", Name: "web"},
+					{Kind: "Ingress", Namespace: "default", Name: "web"},
+				},
+			},
+		},
+		{
+			name: "host network and host port",
+			objects: []string{`
+apiVersion: v1
+kind: Pod
+metadata:
+  name: agent
+  namespace: default
+spec:
+  hostNetwork: true
+  containers:
+    - name: agent
+      image: agent:1.0
+      ports:
+        - containerPort: 9100
+          hostPort: 9100
+`},
+			expected: &Exposure{Level: ExposureNode, HostNetwork: true, HostPorts: []int32{9100}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			artifactList := artifactsFromYAML(t, test.objects...)
+			ClassifyExposure(artifactList)
+			assert.Equal(t, test.expected, artifactList[0].Exposure)
+			for _, a := range artifactList[1:] {
+				assert.Nil(t, a.Exposure)
+			}
+		})
+	}
+}
+
+func artifactsFromYAML(t *testing.T, docs ...string) []*Artifact {
+	artifactList := make([]*Artifact, 0, len(docs))
+	for _, doc := range docs {
+		var u unstructured.Unstructured
+		require.NoError(t, yaml.Unmarshal([]byte(doc), &u.Object))
+		a, err := FromResource(u, map[string]docker.Auth{})
+		require.NoError(t, err)
+		artifactList = append(artifactList, a)
+	}
+	return artifactList
+}
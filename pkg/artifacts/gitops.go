@@ -0,0 +1,180 @@
+package artifacts
+
+import (
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	GitOpsArgoCD = "argocd"
+	GitOpsFlux   = "flux"
+
+	argoTrackingIDAnnotation = "argocd.argoproj.io/tracking-id"
+	argoInstanceLabel        = "argocd.argoproj.io/instance"
+	instanceLabel            = "app.kubernetes.io/instance"
+	argoDefaultNamespace     = "argocd"
+
+	fluxKustomizationNameLabel      = "kustomize.toolkit.fluxcd.io/name"
+	fluxKustomizationNamespaceLabel = "kustomize.toolkit.fluxcd.io/namespace"
+	fluxHelmReleaseNameLabel        = "helm.toolkit.fluxcd.io/name"
+	fluxHelmReleaseNamespaceLabel   = "helm.toolkit.fluxcd.io/namespace"
+)
+
+// GitOpsSource describes the GitOps object managing an artifact and,
+// where available, the repository it is reconciled from
+type GitOpsSource struct {
+	Tool     string
+	Owner    ObjectRef
+	RepoURL  string
+	Path     string
+	Revision string
+}
+
+// gitOpsOwners indexes the GitOps custom resources by kind, namespace and name
+type gitOpsOwners map[ObjectRef]unstructured.Unstructured
+
+// AttributeGitOpsSources sets the GitOpsSource of every artifact managed by Argo CD
+// or Flux, detected from the well-known tracking labels and annotations. Owners are
+// the Argo CD Applications and Flux Kustomizations, HelmReleases and sources used
+// to resolve repository details; GitOps resources found in the artifact list are
+// used as owners as well
+func AttributeGitOpsSources(artifactList []*Artifact, owners []unstructured.Unstructured) {
+	index := make(gitOpsOwners)
+	for _, o := range owners {
+		index[ObjectRef{Kind: o.GetKind(), Namespace: o.GetNamespace(), Name: o.GetName()}] = o
+	}
+	for _, a := range artifactList {
+		if isGitOpsOwner(a.RawResource) {
+			u := unstructured.Unstructured{Object: a.RawResource}
+			index[ObjectRef{Kind: a.Kind, Namespace: a.Namespace, Name: a.Name}] = u
+		}
+	}
+	for _, a := range artifactList {
+		u := unstructured.Unstructured{Object: a.RawResource}
+		if source := index.fluxSource(u.GetLabels()); source != nil {
+			a.GitOpsSource = source
+			continue
+		}
+		if source := index.argoSource(u.GetLabels(), u.GetAnnotations()); source != nil {
+			a.GitOpsSource = source
+		}
+	}
+}
+
+func isGitOpsOwner(obj map[string]interface{}) bool {
+	apiVersion, _, _ := unstructured.NestedString(obj, "apiVersion")
+	return strings.HasPrefix(apiVersion, "argoproj.io/") || strings.Contains(apiVersion, ".toolkit.fluxcd.io/")
+}
+
+func (idx gitOpsOwners) argoSource(labels, annotations map[string]string) *GitOpsSource {
+	var appName string
+	var tracked bool
+	if trackingID, ok := annotations[argoTrackingIDAnnotation]; ok {
+		// <application>:<group>/<kind>:<namespace>/<name>
+		appName, _, _ = strings.Cut(trackingID, ":")
+		tracked = appName != ""
+	} else if name, ok := labels[argoInstanceLabel]; ok {
+		appName, tracked = name, true
+	} else {
+		// app.kubernetes.io/instance is also set by helm charts, trust it only
+		// when a matching Application is known
+		appName = labels[instanceLabel]
+	}
+	if appName == "" {
+		return nil
+	}
+	// applications outside the control plane namespace are tracked as <namespace>_<name>
+	namespace, name, found := strings.Cut(appName, "_")
+	if !found {
+		namespace, name = argoDefaultNamespace, appName
+	}
+	source := &GitOpsSource{
+		Tool:  GitOpsArgoCD,
+		Owner: ObjectRef{Kind: "Application", Namespace: namespace, Name: name},
+	}
+	app, ok := idx[source.Owner]
+	if !ok {
+		app, ok = idx.findByName("Application", name)
+	}
+	if !ok {
+		if !tracked {
+			return nil
+		}
+		return source
+	}
+	source.Owner.Namespace = app.GetNamespace()
+	spec, found, _ := unstructured.NestedMap(app.Object, "spec", "source")
+	if !found {
+		if sources, _, _ := unstructured.NestedSlice(app.Object, "spec", "sources"); len(sources) > 0 {
+			spec, _ = sources[0].(map[string]interface{})
+		}
+	}
+	source.RepoURL, _, _ = unstructured.NestedString(spec, "repoURL")
+	source.Path, _, _ = unstructured.NestedString(spec, "path")
+	if source.Path == "" {
+		source.Path, _, _ = unstructured.NestedString(spec, "chart")
+	}
+	source.Revision, _, _ = unstructured.NestedString(app.Object, "status", "sync", "revision")
+	if source.Revision == "" {
+		source.Revision, _, _ = unstructured.NestedString(spec, "targetRevision")
+	}
+	return source
+}
+
+func (idx gitOpsOwners) fluxSource(labels map[string]string) *GitOpsSource {
+	var source *GitOpsSource
+	switch {
+	case labels[fluxHelmReleaseNameLabel] != "":
+		source = &GitOpsSource{
+			Tool:  GitOpsFlux,
+			Owner: ObjectRef{Kind: "HelmRelease", Namespace: labels[fluxHelmReleaseNamespaceLabel], Name: labels[fluxHelmReleaseNameLabel]},
+		}
+	case labels[fluxKustomizationNameLabel] != "":
+		source = &GitOpsSource{
+			Tool:  GitOpsFlux,
+			Owner: ObjectRef{Kind: "Kustomization", Namespace: labels[fluxKustomizationNamespaceLabel], Name: labels[fluxKustomizationNameLabel]},
+		}
+	default:
+		return nil
+	}
+	owner, ok := idx[source.Owner]
+	if !ok {
+		return source
+	}
+	sourceRefKeys := []string{"spec", "sourceRef"}
+	if source.Owner.Kind == "HelmRelease" {
+		sourceRefKeys = []string{"spec", "chart", "spec", "sourceRef"}
+		source.Path, _, _ = unstructured.NestedString(owner.Object, "spec", "chart", "spec", "chart")
+	} else {
+		source.Path, _, _ = unstructured.NestedString(owner.Object, "spec", "path")
+	}
+	source.Revision, _, _ = unstructured.NestedString(owner.Object, "status", "lastAppliedRevision")
+
+	sourceRef, _, _ := unstructured.NestedStringMap(owner.Object, sourceRefKeys...)
+	sourceNamespace := sourceRef["namespace"]
+	if sourceNamespace == "" {
+		sourceNamespace = source.Owner.Namespace
+	}
+	if repo, ok := idx[ObjectRef{Kind: sourceRef["kind"], Namespace: sourceNamespace, Name: sourceRef["name"]}]; ok {
+		source.RepoURL, _, _ = unstructured.NestedString(repo.Object, "spec", "url")
+		if source.Revision == "" {
+			source.Revision, _, _ = unstructured.NestedString(repo.Object, "status", "artifact", "revision")
+		}
+	}
+	return source
+}
+
+// findByName returns the only owner of a kind with the given name
+func (idx gitOpsOwners) findByName(kind, name string) (unstructured.Unstructured, bool) {
+	var found []unstructured.Unstructured
+	for ref, o := range idx {
+		if ref.Kind == kind && ref.Name == name {
+			found = append(found, o)
+		}
+	}
+	if len(found) != 1 {
+		return unstructured.Unstructured{}, false
+	}
+	return found[0], true
+}
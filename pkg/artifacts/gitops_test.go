@@ -0,0 +1,162 @@
+package artifacts
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestAttributeGitOpsSources(t *testing.T) {
+	owners := []unstructured.Unstructured{
+		{Object: map[string]interface{}{
+			"apiVersion": "argoproj.io/v1alpha1",
+			"kind":       "Application",
+			"metadata":   map[string]interface{}{"name": "shop", "namespace": "argocd"},
+			"spec": map[string]interface{}{
+				"source": map[string]interface{}{
+					"repoURL":        "https://github.com/acme/deploy.git",
+					"path":           "apps/shop",
+					"targetRevision": "main",
+				},
+			},
+			"status": map[string]interface{}{
+				"sync": map[string]interface{}{"revision": "8f2c1e0"},
+			},
+		}},
+		{Object: map[string]interface{}{
+			"apiVersion": "kustomize.toolkit.fluxcd.io/v1",
+			"kind":       "Kustomization",
+			"metadata":   map[string]interface{}{"name": "infra", "namespace": "flux-system"},
+			"spec": map[string]interface{}{
+				"path":      "./infrastructure",
+				"sourceRef": map[string]interface{}{"kind": "GitRepository", "name": "fleet"},
+			},
+			"status": map[string]interface{}{"lastAppliedRevision": "main@sha1:5e4f3d2"},
+		}},
+		{Object: map[string]interface{}{
+			"apiVersion": "source.toolkit.fluxcd.io/v1",
+			"kind":       "GitRepository",
+			"metadata":   map[string]interface{}{"name": "fleet", "namespace": "flux-system"},
+			"spec":       map[string]interface{}{"url": "https://github.com/acme/fleet"},
+		}},
+	}
+
+	tests := []struct {
+		name     string
+		object   string
+		expected *GitOpsSource
+	}{
+		{
+			name: "argo cd tracking id",
+			object: `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: shop
+  namespace: shop
+  annotations:
+    argocd.argoproj.io/tracking-id: shop:apps/Deployment:shop/shop
+`,
+			expected: &GitOpsSource{
+				Tool:     GitOpsArgoCD,
+				Owner:    ObjectRef{Kind: "Application", Namespace: "argocd", Name: "shop"},
+				RepoURL:  "https://github.com/acme/deploy.git",
+				Path:     "apps/shop",
+				Revision: "8f2c1e0",
+			},
+		},
+		{
+			name: "argo cd tracking id of an unknown application",
+			object: `
+apiVersion: v1
+kind: Service
+metadata:
+  name: api
+  namespace: team
+  annotations:
+    argocd.argoproj.io/tracking-id: team_api:/Service:team/api
+`,
+			expected: &GitOpsSource{
+				Tool:  GitOpsArgoCD,
+				Owner: ObjectRef{Kind: "Application", Namespace: "team", Name: "api"},
+			},
+		},
+		{
+			name: "argo cd instance label",
+			object: `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: shop
+  namespace: shop
+  labels:
+    app.kubernetes.io/instance: shop
+`,
+			expected: &GitOpsSource{
+				Tool:     GitOpsArgoCD,
+				Owner:    ObjectRef{Kind: "Application", Namespace: "argocd", Name: "shop"},
+				RepoURL:  "https://github.com/acme/deploy.git",
+				Path:     "apps/shop",
+				Revision: "8f2c1e0",
+			},
+		},
+		{
+			name: "helm instance label without application",
+			object: `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: redis
+  namespace: cache
+  labels:
+    app.kubernetes.io/instance: redis
+`,
+		},
+		{
+			name: "flux kustomization labels",
+			object: `
+apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: agent
+  namespace: monitoring
+  labels:
+    kustomize.toolkit.fluxcd.io/name: infra
+    kustomize.toolkit.fluxcd.io/namespace: flux-system
+`,
+			expected: &GitOpsSource{
+				Tool:     GitOpsFlux,
+				Owner:    ObjectRef{Kind: "Kustomization", Namespace: "flux-system", Name: "infra"},
+				RepoURL:  "https://github.com/acme/fleet",
+				Path:     "./infrastructure",
+				Revision: "main@sha1:5e4f3d2",
+			},
+		},
+		{
+			name: "flux helm release labels",
+			object: `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: podinfo
+  namespace: apps
+  labels:
+    helm.toolkit.fluxcd.io/name: podinfo
+    helm.toolkit.fluxcd.io/namespace: apps
+`,
+			expected: &GitOpsSource{
+				Tool:  GitOpsFlux,
+				Owner: ObjectRef{Kind: "HelmRelease", Namespace: "apps", Name: "podinfo"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			artifactList := artifactsFromYAML(t, test.object)
+			AttributeGitOpsSources(artifactList, owners)
+			assert.Equal(t, test.expected, artifactList[0].GitOpsSource)
+		})
+	}
+}
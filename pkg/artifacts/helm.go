@@ -0,0 +1,42 @@
+package artifacts
+
+import (
+	"encoding/json"
+
+	"github.com/aquasecurity/trivy-kubernetes/pkg/bom"
+)
+
+// KindHelmChartRelease is the kind of artifacts describing a helm release, unlike
+// the HelmRelease of Flux which is the resource declaring one
+const KindHelmChartRelease = "HelmChartRelease"
+
+// MapHelmReleases sets the HelmRelease of every artifact rendered by one of the
+// HelmChartRelease artifacts found in the same list
+func MapHelmReleases(artifactList []*Artifact) {
+	releases := make(map[bom.Resource]*ObjectRef)
+	for _, a := range artifactList {
+		if a.Kind != KindHelmChartRelease {
+			continue
+		}
+		b, err := json.Marshal(a.RawResource["Resources"])
+		if err != nil {
+			continue
+		}
+		var resources []bom.Resource
+		if err := json.Unmarshal(b, &resources); err != nil {
+			continue
+		}
+		release := &ObjectRef{Kind: a.Kind, Namespace: a.Namespace, Name: a.Name}
+		for _, r := range resources {
+			releases[r] = release
+		}
+	}
+	if len(releases) == 0 {
+		return
+	}
+	for _, a := range artifactList {
+		if release, ok := releases[bom.Resource{Kind: a.Kind, Namespace: a.Namespace, Name: a.Name}]; ok {
+			a.HelmRelease = release
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package artifacts
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMapHelmReleases(t *testing.T) {
+	artifactList := artifactsFromYAML(t, `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web-nginx
+  namespace: apps
+`, `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web-nginx
+  namespace: other
+`)
+	artifactList = append(artifactList, &Artifact{
+		Kind:      KindHelmChartRelease,
+		Namespace: "apps",
+		Name:      "web",
+		RawResource: map[string]interface{}{
+			"Name": "nginx",
+			"Resources": []interface{}{
+				map[string]interface{}{"Kind": "Deployment", "Namespace": "apps", "Name": "web-nginx"},
+			},
+		},
+	})
+
+	MapHelmReleases(artifactList)
+	assert.Equal(t, &ObjectRef{Kind: KindHelmChartRelease, Namespace: "apps", Name: "web"}, artifactList[0].HelmRelease)
+	assert.Nil(t, artifactList[1].HelmRelease)
+	assert.Nil(t, artifactList[2].HelmRelease)
+}
@@ -0,0 +1,148 @@
+package artifacts
+
+import (
+	"strings"
+
+	containerimage "github.com/google/go-containerregistry/pkg/name"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/aquasecurity/trivy-kubernetes/utils"
+)
+
+const dockerHubRegistry = "docker.io"
+
+// ImageHygiene holds the supply-chain flags of a container image reference
+type ImageHygiene struct {
+	Container string
+	Image     string
+	// Registry is the registry the image is pulled from, docker.io for Docker Hub
+	Registry string
+	Tag      string
+	Digest   string
+	// DigestPinned is true when the image is referenced by digest
+	DigestPinned bool
+	// ImplicitLatest is true when the image has neither a tag nor a digest
+	ImplicitLatest bool
+	// ImplicitRegistry is true when the image defaults to Docker Hub
+	ImplicitRegistry bool
+	// PullPolicyMismatch is true when a mutable tag is pulled with IfNotPresent
+	PullPolicyMismatch bool
+	// DisallowedRegistry is true when the registry is not in the allow-list
+	DisallowedRegistry bool
+	// Invalid is true when the reference can't be parsed, no other flag is set
+	Invalid bool
+}
+
+// AnalyzeImageHygiene sets the ImageHygiene of every container of the workload
+// artifacts. Allowed registries are registry hosts (ghcr.io), wildcard hosts
+// (*.example.com) or repository prefixes (ghcr.io/acme); no registry is flagged
+// when the allow-list is empty
+func AnalyzeImageHygiene(artifactList []*Artifact, allowedRegistries []string) {
+	for _, a := range artifactList {
+		spec := a.PodSpec()
+		if spec == nil {
+			continue
+		}
+		hygiene := make([]ImageHygiene, 0)
+		for _, c := range append(spec.InitContainers, spec.Containers...) {
+			hygiene = append(hygiene, imageHygiene(c.Name, c.Image, c.ImagePullPolicy, allowedRegistries))
+		}
+		for _, c := range spec.EphemeralContainers {
+			hygiene = append(hygiene, imageHygiene(c.Name, c.Image, c.ImagePullPolicy, allowedRegistries))
+		}
+		a.ImageHygiene = hygiene
+	}
+}
+
+func imageHygiene(container, image string, pullPolicy corev1.PullPolicy, allowedRegistries []string) ImageHygiene {
+	h := ImageHygiene{Container: container, Image: image}
+	ref, err := utils.ParseReference(image)
+	if err != nil {
+		h.Invalid = true
+		return h
+	}
+	h.ImplicitRegistry = !hasExplicitRegistry(image)
+	h.Registry = ref.Context().RegistryStr()
+	repositoryStr := ref.Context().RepositoryStr()
+	switch {
+	case strings.HasPrefix(image, "localhost/"):
+		// the runtime pulls from localhost where the parser defaults to docker hub
+		h.Registry = "localhost"
+		repositoryStr = strings.TrimPrefix(repositoryStr, "localhost/")
+	case h.Registry == containerimage.DefaultRegistry:
+		h.Registry = dockerHubRegistry
+	}
+	repository := h.Registry + "/" + repositoryStr
+
+	switch r := ref.(type) {
+	case containerimage.Digest:
+		h.DigestPinned = true
+		h.Digest = r.DigestStr()
+		// a tag next to the digest is ignored by the runtime but still informative
+		name, _, _ := strings.Cut(image, "@")
+		h.Tag = referenceTag(name)
+	case containerimage.Tag:
+		h.Tag = referenceTag(image)
+		h.ImplicitLatest = h.Tag == ""
+		if h.ImplicitLatest {
+			h.Tag = r.TagStr()
+		}
+	}
+
+	if pullPolicy == "" {
+		// defaulted by the api server when not set
+		pullPolicy = corev1.PullIfNotPresent
+		if h.Tag == "latest" && !h.DigestPinned {
+			pullPolicy = corev1.PullAlways
+		}
+	}
+	h.PullPolicyMismatch = pullPolicy == corev1.PullIfNotPresent && !h.DigestPinned
+
+	if len(allowedRegistries) > 0 {
+		h.DisallowedRegistry = !registryAllowed(h.Registry, repository, allowedRegistries)
+	}
+	return h
+}
+
+// referenceTag returns the tag written in an image reference without digest
+func referenceTag(name string) string {
+	lastSegment := name[strings.LastIndex(name, "/")+1:]
+	_, tag, _ := strings.Cut(lastSegment, ":")
+	return tag
+}
+
+// hasExplicitRegistry follows the docker rule: the first path component is a
+// registry when it contains a dot or a port, or is localhost
+func hasExplicitRegistry(image string) bool {
+	if strings.HasPrefix(image, "arn:aws:ecr") {
+		return true
+	}
+	first, _, found := strings.Cut(image, "/")
+	if !found {
+		return false
+	}
+	return strings.ContainsAny(first, ".:") || first == "localhost"
+}
+
+func registryAllowed(registry, repository string, allowedRegistries []string) bool {
+	for _, allowed := range allowedRegistries {
+		allowed = strings.TrimSuffix(allowed, "/")
+		if allowed == containerimage.DefaultRegistry {
+			allowed = dockerHubRegistry
+		}
+		switch {
+		case strings.HasPrefix(allowed, "*."):
+			host, _, _ := strings.Cut(registry, ":")
+			if strings.HasSuffix(host, allowed[1:]) {
+				return true
+			}
+		case strings.Contains(allowed, "/"):
+			if strings.HasPrefix(repository+"/", allowed+"/") {
+				return true
+			}
+		case registry == allowed:
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,137 @@
+package artifacts
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestImageHygiene(t *testing.T) {
+	allowed := []string{"docker.io/library", "*.example.com", "ghcr.io"}
+	tests := []struct {
+		name       string
+		image      string
+		pullPolicy corev1.PullPolicy
+		expected   ImageHygiene
+	}{
+		{
+			name:  "implicit latest from docker hub",
+			image: "nginx",
+			expected: ImageHygiene{
+				Registry:         "docker.io",
+				Tag:              "latest",
+				ImplicitLatest:   true,
+				ImplicitRegistry: true,
+			},
+		},
+		{
+			name:  "mutable tag pulled if not present",
+			image: "acme/api:1.2",
+			expected: ImageHygiene{
+				Registry:           "docker.io",
+				Tag:                "1.2",
+				ImplicitRegistry:   true,
+				PullPolicyMismatch: true,
+				DisallowedRegistry: true,
+			},
+		},
+		{
+			name:       "mutable tag always pulled",
+			image:      "ghcr.io/acme/api:1.2",
+			pullPolicy: corev1.PullAlways,
+			expected: ImageHygiene{
+				Registry: "ghcr.io",
+				Tag:      "1.2",
+			},
+		},
+		{
+			name:       "digest pinned with tag",
+			image:      "registry.example.com:5000/api:1.2@sha256:3e2ba2b7f8dd6a1a7ae3a8d6d9e0a5f6d0e8d1f3b0b6c5a1f9a8e7d6c5b4a3f2",
+			pullPolicy: corev1.PullIfNotPresent,
+			expected: ImageHygiene{
+				Registry:     "registry.example.com:5000",
+				Tag:          "1.2",
+				Digest:       "sha256:3e2ba2b7f8dd6a1a7ae3a8d6d9e0a5f6d0e8d1f3b0b6c5a1f9a8e7d6c5b4a3f2",
+				DigestPinned: true,
+			},
+		},
+		{
+			name:       "explicit docker hub registry",
+			image:      "docker.io/library/redis:7",
+			pullPolicy: corev1.PullNever,
+			expected: ImageHygiene{
+				Registry: "docker.io",
+				Tag:      "7",
+			},
+		},
+		{
+			name:  "localhost registry",
+			image: "localhost/api",
+			expected: ImageHygiene{
+				Registry:           "localhost",
+				Tag:                "latest",
+				ImplicitLatest:     true,
+				DisallowedRegistry: true,
+			},
+		},
+		{
+			name:     "invalid reference",
+			image:    "Invalid Image",
+			expected: ImageHygiene{Invalid: true},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.expected.Container = "app"
+			test.expected.Image = test.image
+			assert.Equal(t, test.expected, imageHygiene("app", test.image, test.pullPolicy, allowed))
+		})
+	}
+}
+
+func TestAnalyzeImageHygiene(t *testing.T) {
+	artifactList := artifactsFromYAML(t, `
+apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: backup
+  namespace: ops
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          initContainers:
+            - name: init
+              image: busybox:1.36
+          containers:
+            - name: backup
+              image: quay.io/acme/backup:latest
+`, `
+apiVersion: v1
+kind: Service
+metadata:
+  name: backup
+  namespace: ops
+`)
+	AnalyzeImageHygiene(artifactList, nil)
+
+	assert.Equal(t, []ImageHygiene{
+		{
+			Container:          "init",
+			Image:              "busybox:1.36",
+			Registry:           "docker.io",
+			Tag:                "1.36",
+			ImplicitRegistry:   true,
+			PullPolicyMismatch: true,
+		},
+		{
+			Container: "backup",
+			Image:     "quay.io/acme/backup:latest",
+			Registry:  "quay.io",
+			Tag:       "latest",
+		},
+	}, artifactList[0].ImageHygiene)
+	assert.Nil(t, artifactList[1].ImageHygiene)
+}
@@ -0,0 +1,249 @@
+package artifacts
+
+import (
+	"sort"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// namespaceNameLabel is set by the api server on every namespace to its name
+const namespaceNameLabel = "kubernetes.io/metadata.name"
+
+// NamespaceLabels are the labels of the namespaces by name
+type NamespaceLabels map[string]map[string]string
+
+// NetworkPolicyCoverage describes which NetworkPolicies select a workload
+type NetworkPolicyCoverage struct {
+	// Ingress is true when the policies selecting the workload restrict incoming
+	// traffic, i.e. at least one applies to ingress and none has a rule allowing
+	// traffic from anywhere on any port
+	Ingress bool
+	// Egress is true when the policies selecting the workload restrict outgoing
+	// traffic, in the same way
+	Egress          bool
+	IngressPolicies []ObjectRef
+	EgressPolicies  []ObjectRef
+	// IngressFromAllNamespaces is true when a selecting policy admits traffic
+	// from pods of every namespace
+	IngressFromAllNamespaces bool
+	// EgressToAllNamespaces is true when a selecting policy admits traffic
+	// to pods of every namespace
+	EgressToAllNamespaces bool
+}
+
+// NetworkPolicySummary describes the NetworkPolicies of a namespace
+type NetworkPolicySummary struct {
+	Namespace            string
+	Policies             int
+	DefaultDenyIngress   bool
+	DefaultDenyEgress    bool
+	UnprotectedWorkloads []ObjectRef
+}
+
+type networkPolicy struct {
+	ref      ObjectRef
+	selector labels.Selector
+	ingress  bool
+	egress   bool
+	spec     networkingv1.NetworkPolicySpec
+}
+
+// AnalyzeNetworkPolicies sets the NetworkPolicyCoverage of every workload artifact
+// using the NetworkPolicy artifacts found in the same list. The namespaceSelectors
+// are evaluated against the namespace labels, every namespace of the list has at
+// least its kubernetes.io/metadata.name label
+func AnalyzeNetworkPolicies(artifactList []*Artifact, namespaceLabels NamespaceLabels) {
+	policies := networkPoliciesByNamespace(artifactList)
+	namespaces := knownNamespaces(artifactList, namespaceLabels)
+	for _, a := range artifactList {
+		if !a.IsWorkload() {
+			continue
+		}
+		coverage := &NetworkPolicyCoverage{}
+		var ingressOpen, egressOpen bool
+		podLabels := labels.Set(a.PodLabels())
+		for _, p := range policies[a.Namespace] {
+			if !p.selector.Matches(podLabels) {
+				continue
+			}
+			if p.ingress {
+				coverage.Ingress = true
+				coverage.IngressPolicies = append(coverage.IngressPolicies, p.ref)
+				for _, rule := range p.spec.Ingress {
+					ingressOpen = ingressOpen || (len(rule.From) == 0 && len(rule.Ports) == 0)
+					if admitsAllNamespaces(rule.From, namespaces) {
+						coverage.IngressFromAllNamespaces = true
+					}
+				}
+			}
+			if p.egress {
+				coverage.Egress = true
+				coverage.EgressPolicies = append(coverage.EgressPolicies, p.ref)
+				for _, rule := range p.spec.Egress {
+					egressOpen = egressOpen || (len(rule.To) == 0 && len(rule.Ports) == 0)
+					if admitsAllNamespaces(rule.To, namespaces) {
+						coverage.EgressToAllNamespaces = true
+					}
+				}
+			}
+		}
+		// the rules of the policies add up, one allowing everything lifts the restriction
+		coverage.Ingress = coverage.Ingress && !ingressOpen
+		coverage.Egress = coverage.Egress && !egressOpen
+		a.NetworkPolicyCoverage = coverage
+	}
+}
+
+// knownNamespaces returns the labels of the given namespaces and of those of the
+// artifacts, with their kubernetes.io/metadata.name label
+func knownNamespaces(artifactList []*Artifact, namespaceLabels NamespaceLabels) []labels.Set {
+	names := make(map[string]bool)
+	for name := range namespaceLabels {
+		names[name] = true
+	}
+	for _, a := range artifactList {
+		if a.Namespace != "" {
+			names[a.Namespace] = true
+		}
+	}
+	namespaces := make([]labels.Set, 0, len(names))
+	for name := range names {
+		set := labels.Set{namespaceNameLabel: name}
+		for k, v := range namespaceLabels[name] {
+			set[k] = v
+		}
+		namespaces = append(namespaces, set)
+	}
+	return namespaces
+}
+
+// SummarizeNetworkPolicies returns a summary per namespace holding workloads or
+// NetworkPolicies; workloads need to be analyzed with AnalyzeNetworkPolicies first
+func SummarizeNetworkPolicies(artifactList []*Artifact) []NetworkPolicySummary {
+	summaries := make(map[string]*NetworkPolicySummary)
+	summary := func(namespace string) *NetworkPolicySummary {
+		if _, ok := summaries[namespace]; !ok {
+			summaries[namespace] = &NetworkPolicySummary{Namespace: namespace}
+		}
+		return summaries[namespace]
+	}
+	for namespace, policies := range networkPoliciesByNamespace(artifactList) {
+		s := summary(namespace)
+		s.Policies = len(policies)
+		for _, p := range policies {
+			if !p.selector.Empty() {
+				continue
+			}
+			if p.ingress && len(p.spec.Ingress) == 0 {
+				s.DefaultDenyIngress = true
+			}
+			if p.egress && len(p.spec.Egress) == 0 {
+				s.DefaultDenyEgress = true
+			}
+		}
+	}
+	for _, a := range artifactList {
+		if !a.IsWorkload() {
+			continue
+		}
+		s := summary(a.Namespace)
+		if a.NetworkPolicyCoverage == nil || !a.NetworkPolicyCoverage.Ingress {
+			s.UnprotectedWorkloads = append(s.UnprotectedWorkloads, ObjectRef{Kind: a.Kind, Namespace: a.Namespace, Name: a.Name})
+		}
+	}
+
+	result := make([]NetworkPolicySummary, 0, len(summaries))
+	for _, s := range summaries {
+		result = append(result, *s)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Namespace < result[j].Namespace
+	})
+	return result
+}
+
+func networkPoliciesByNamespace(artifactList []*Artifact) map[string][]networkPolicy {
+	policies := make(map[string][]networkPolicy)
+	for _, a := range artifactList {
+		if a.Kind != "NetworkPolicy" {
+			continue
+		}
+		var np networkingv1.NetworkPolicy
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(a.RawResource, &np); err != nil {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(&np.Spec.PodSelector)
+		if err != nil {
+			continue
+		}
+		ingress, egress := policyTypes(np.Spec)
+		policies[a.Namespace] = append(policies[a.Namespace], networkPolicy{
+			ref:      ObjectRef{Kind: a.Kind, Namespace: a.Namespace, Name: a.Name},
+			selector: selector,
+			ingress:  ingress,
+			egress:   egress,
+			spec:     np.Spec,
+		})
+	}
+	return policies
+}
+
+// policyTypes returns the directions a policy applies to; when policyTypes is
+// not set, a policy always applies to ingress and to egress only if it has egress rules
+func policyTypes(spec networkingv1.NetworkPolicySpec) (bool, bool) {
+	if len(spec.PolicyTypes) == 0 {
+		return true, len(spec.Egress) > 0
+	}
+	var ingress, egress bool
+	for _, t := range spec.PolicyTypes {
+		switch t {
+		case networkingv1.PolicyTypeIngress:
+			ingress = true
+		case networkingv1.PolicyTypeEgress:
+			egress = true
+		}
+	}
+	return ingress, egress
+}
+
+// admitsAllNamespaces returns true if a rule admits pods of every namespace: a rule
+// without peers matches all sources, and a peer whose namespaceSelector matches
+// every namespace and without restricting podSelector matches every pod of them
+func admitsAllNamespaces(peers []networkingv1.NetworkPolicyPeer, namespaces []labels.Set) bool {
+	if len(peers) == 0 {
+		return true
+	}
+	for _, peer := range peers {
+		if peer.NamespaceSelector == nil || !selectsAll(peer.NamespaceSelector, namespaces) {
+			continue
+		}
+		if peer.PodSelector == nil || isEmptySelector(peer.PodSelector) {
+			return true
+		}
+	}
+	return false
+}
+
+// selectsAll reports whether the selector matches every namespace
+func selectsAll(selector *metav1.LabelSelector, namespaces []labels.Set) bool {
+	if isEmptySelector(selector) {
+		return true
+	}
+	s, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil || len(namespaces) == 0 {
+		return false
+	}
+	for _, ns := range namespaces {
+		if !s.Matches(ns) {
+			return false
+		}
+	}
+	return true
+}
+
+func isEmptySelector(selector *metav1.LabelSelector) bool {
+	return len(selector.MatchLabels) == 0 && len(selector.MatchExpressions) == 0
+}
@@ -0,0 +1,219 @@
+package artifacts
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalyzeNetworkPolicies(t *testing.T) {
+	workloads := []string{`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: app
+spec:
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      containers:
+        - name: web
+          image: nginx:1.25
+`, `
+apiVersion: v1
+kind: Pod
+metadata:
+  name: db
+  namespace: app
+  labels:
+    app: db
+spec:
+  containers:
+    - name: db
+      image: postgres:16
+`, `
+apiVersion: v1
+kind: Pod
+metadata:
+  name: tool
+  namespace: tools
+  labels:
+    app: tool
+spec:
+  containers:
+    - name: tool
+      image: busybox:1.36
+`}
+	policies := []string{`
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: default-deny
+  namespace: app
+spec:
+  podSelector: {}
+  policyTypes:
+    - Ingress
+    - Egress
+`, `
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: web-from-all
+  namespace: app
+spec:
+  podSelector:
+    matchExpressions:
+      - key: app
+        operator: In
+        values: ["web"]
+  ingress:
+    - from:
+        - namespaceSelector: {}
+`, `
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: db-egress
+  namespace: app
+spec:
+  podSelector:
+    matchLabels:
+      app: db
+  egress:
+    - to:
+        - namespaceSelector:
+            matchLabels:
+              kubernetes.io/metadata.name: app
+`}
+	artifactList := artifactsFromYAML(t, append(workloads, policies...)...)
+	AnalyzeNetworkPolicies(artifactList, nil)
+
+	defaultDeny := ObjectRef{Kind: "NetworkPolicy", Namespace: "app", Name: "default-deny"}
+	dbEgress := ObjectRef{Kind: "NetworkPolicy", Namespace: "app", Name: "db-egress"}
+	assert.Equal(t, &NetworkPolicyCoverage{
+		Ingress:                  true,
+		Egress:                   true,
+		IngressPolicies:          []ObjectRef{defaultDeny, {Kind: "NetworkPolicy", Namespace: "app", Name: "web-from-all"}},
+		EgressPolicies:           []ObjectRef{defaultDeny},
+		IngressFromAllNamespaces: true,
+	}, artifactList[0].NetworkPolicyCoverage)
+	assert.Equal(t, &NetworkPolicyCoverage{
+		Ingress: true,
+		Egress:  true,
+		// policies without policyTypes always apply to ingress
+		IngressPolicies: []ObjectRef{defaultDeny, dbEgress},
+		EgressPolicies:  []ObjectRef{defaultDeny, dbEgress},
+	}, artifactList[1].NetworkPolicyCoverage)
+	assert.Equal(t, &NetworkPolicyCoverage{}, artifactList[2].NetworkPolicyCoverage)
+	for _, a := range artifactList[len(workloads):] {
+		assert.Nil(t, a.NetworkPolicyCoverage)
+	}
+
+	summaries := SummarizeNetworkPolicies(artifactList)
+	require.Len(t, summaries, 2)
+	assert.Equal(t, NetworkPolicySummary{
+		Namespace:          "app",
+		Policies:           3,
+		DefaultDenyIngress: true,
+		DefaultDenyEgress:  true,
+	}, summaries[0])
+	assert.Equal(t, NetworkPolicySummary{
+		Namespace:            "tools",
+		UnprotectedWorkloads: []ObjectRef{{Kind: "Pod", Namespace: "tools", Name: "tool"}},
+	}, summaries[1])
+}
+
+func TestAnalyzeNetworkPoliciesRules(t *testing.T) {
+	workload := `
+apiVersion: v1
+kind: Pod
+metadata:
+  name: web
+  namespace: app
+  labels:
+    app: web
+spec:
+  containers:
+    - name: web
+      image: nginx:1.25
+`
+	policy := func(ingress string) string {
+		return `
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: web
+  namespace: app
+spec:
+  podSelector: {}
+  policyTypes:
+    - Ingress
+  ingress:
+` + ingress
+	}
+	namespaceLabels := NamespaceLabels{
+		"app":     {"team": "web"},
+		"monitor": {"team": "ops"},
+	}
+
+	tests := []struct {
+		name               string
+		ingress            string
+		namespaceLabels    NamespaceLabels
+		wantIngress        bool
+		wantFromAll        bool
+		wantUnprotectedWeb bool
+	}{
+		{
+			name:               "rule allowing everything",
+			ingress:            "    - {}\n",
+			wantFromAll:        true,
+			wantUnprotectedWeb: true,
+		},
+		{
+			name:        "rule restricted to a port",
+			ingress:     "    - ports:\n        - port: 80\n",
+			wantIngress: true,
+			wantFromAll: true,
+		},
+		{
+			name:            "namespaceSelector matching every namespace",
+			ingress:         "    - from:\n        - namespaceSelector:\n            matchExpressions:\n              - {key: team, operator: Exists}\n",
+			namespaceLabels: namespaceLabels,
+			wantIngress:     true,
+			wantFromAll:     true,
+		},
+		{
+			name:            "namespaceSelector matching some namespaces",
+			ingress:         "    - from:\n        - namespaceSelector:\n            matchLabels:\n              team: ops\n",
+			namespaceLabels: namespaceLabels,
+			wantIngress:     true,
+		},
+		{
+			name:        "namespaceSelector on the name label",
+			ingress:     "    - from:\n        - namespaceSelector:\n            matchExpressions:\n              - {key: kubernetes.io/metadata.name, operator: Exists}\n",
+			wantIngress: true,
+			wantFromAll: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			artifactList := artifactsFromYAML(t, workload, policy(test.ingress))
+			AnalyzeNetworkPolicies(artifactList, test.namespaceLabels)
+
+			coverage := artifactList[0].NetworkPolicyCoverage
+			require.NotNil(t, coverage)
+			assert.Equal(t, test.wantIngress, coverage.Ingress)
+			assert.Equal(t, test.wantFromAll, coverage.IngressFromAllNamespaces)
+			summaries := SummarizeNetworkPolicies(artifactList)
+			require.Len(t, summaries, 1)
+			assert.Equal(t, test.wantUnprotectedWeb, len(summaries[0].UnprotectedWorkloads) == 1)
+		})
+	}
+}
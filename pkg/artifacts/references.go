@@ -0,0 +1,181 @@
+package artifacts
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/ptr"
+)
+
+const (
+	KindConfigMap = "ConfigMap"
+	KindSecret    = "Secret"
+
+	// reference sources in the pod spec
+	RefSourceEnv             = "env"
+	RefSourceEnvFrom         = "envFrom"
+	RefSourceVolume          = "volume"
+	RefSourceProjected       = "projected"
+	RefSourceImagePullSecret = "imagePullSecrets"
+)
+
+// ConfigReference is a reference from a pod spec to a ConfigMap or a Secret
+type ConfigReference struct {
+	ObjectRef
+	Source   string
+	Optional bool
+	// Missing is true when the referenced object does not exist
+	Missing bool
+	// Dangling is true when the referenced object does not exist and the reference
+	// is not optional, optional references are valid without their object
+	Dangling bool
+}
+
+// ConfigInventory holds the existing ConfigMaps and Secrets by kind, references
+// are only checked for kinds present in the inventory
+type ConfigInventory map[string][]ObjectRef
+
+// AnalyzeConfigReferences sets the ConfigReferences of every workload artifact and
+// flags the ones to objects missing from the inventory, which are dangling unless
+// optional. ConfigMap and Secret
+// artifacts found in the list are added to the inventory
+func AnalyzeConfigReferences(artifactList []*Artifact, inventory ConfigInventory) {
+	existing := make(map[string]map[ObjectRef]bool)
+	for kind, refs := range inventory {
+		existing[kind] = make(map[ObjectRef]bool)
+		for _, ref := range refs {
+			existing[kind][ref] = true
+		}
+	}
+	for _, a := range artifactList {
+		if a.Kind != KindConfigMap && a.Kind != KindSecret {
+			continue
+		}
+		if existing[a.Kind] == nil {
+			existing[a.Kind] = make(map[ObjectRef]bool)
+		}
+		existing[a.Kind][ObjectRef{Kind: a.Kind, Namespace: a.Namespace, Name: a.Name}] = true
+	}
+
+	for _, a := range artifactList {
+		spec := a.PodSpec()
+		if spec == nil {
+			continue
+		}
+		refs := podSpecConfigReferences(spec, a.Namespace)
+		for i := range refs {
+			if objects, ok := existing[refs[i].Kind]; ok {
+				refs[i].Missing = !objects[refs[i].ObjectRef]
+				refs[i].Dangling = refs[i].Missing && !refs[i].Optional
+			}
+		}
+		a.ConfigReferences = refs
+	}
+}
+
+// DanglingConfigReferences returns the workloads required references to ConfigMaps
+// or Secrets that do not exist, as analyzed by AnalyzeConfigReferences
+func DanglingConfigReferences(artifactList []*Artifact) map[ObjectRef][]ConfigReference {
+	dangling := make(map[ObjectRef][]ConfigReference)
+	for _, a := range artifactList {
+		for _, ref := range a.ConfigReferences {
+			if ref.Dangling {
+				workload := ObjectRef{Kind: a.Kind, Namespace: a.Namespace, Name: a.Name}
+				dangling[workload] = append(dangling[workload], ref)
+			}
+		}
+	}
+	return dangling
+}
+
+// FilterReferencedConfig drops the ConfigMap and Secret artifacts which are not
+// referenced by any workload, as analyzed by AnalyzeConfigReferences
+func FilterReferencedConfig(artifactList []*Artifact) []*Artifact {
+	referenced := make(map[ObjectRef]bool)
+	for _, a := range artifactList {
+		for _, ref := range a.ConfigReferences {
+			referenced[ref.ObjectRef] = true
+		}
+	}
+	filtered := make([]*Artifact, 0, len(artifactList))
+	for _, a := range artifactList {
+		if (a.Kind == KindConfigMap || a.Kind == KindSecret) &&
+			!referenced[ObjectRef{Kind: a.Kind, Namespace: a.Namespace, Name: a.Name}] {
+			continue
+		}
+		filtered = append(filtered, a)
+	}
+	return filtered
+}
+
+func podSpecConfigReferences(spec *corev1.PodSpec, namespace string) []ConfigReference {
+	refs := make([]ConfigReference, 0)
+	seen := make(map[ConfigReference]bool)
+	add := func(kind, name, source string, optional *bool) {
+		if name == "" {
+			return
+		}
+		ref := ConfigReference{
+			ObjectRef: ObjectRef{Kind: kind, Namespace: namespace, Name: name},
+			Source:    source,
+			Optional:  ptr.Deref(optional, false),
+		}
+		if !seen[ref] {
+			seen[ref] = true
+			refs = append(refs, ref)
+		}
+	}
+
+	envs := make([][]corev1.EnvVar, 0)
+	envFroms := make([][]corev1.EnvFromSource, 0)
+	for _, c := range append(spec.InitContainers, spec.Containers...) {
+		envs, envFroms = append(envs, c.Env), append(envFroms, c.EnvFrom)
+	}
+	for _, c := range spec.EphemeralContainers {
+		envs, envFroms = append(envs, c.Env), append(envFroms, c.EnvFrom)
+	}
+	for _, env := range envs {
+		for _, e := range env {
+			if e.ValueFrom == nil {
+				continue
+			}
+			if ref := e.ValueFrom.ConfigMapKeyRef; ref != nil {
+				add(KindConfigMap, ref.Name, RefSourceEnv, ref.Optional)
+			}
+			if ref := e.ValueFrom.SecretKeyRef; ref != nil {
+				add(KindSecret, ref.Name, RefSourceEnv, ref.Optional)
+			}
+		}
+	}
+	for _, envFrom := range envFroms {
+		for _, e := range envFrom {
+			if ref := e.ConfigMapRef; ref != nil {
+				add(KindConfigMap, ref.Name, RefSourceEnvFrom, ref.Optional)
+			}
+			if ref := e.SecretRef; ref != nil {
+				add(KindSecret, ref.Name, RefSourceEnvFrom, ref.Optional)
+			}
+		}
+	}
+	for _, v := range spec.Volumes {
+		if v.ConfigMap != nil {
+			add(KindConfigMap, v.ConfigMap.Name, RefSourceVolume, v.ConfigMap.Optional)
+		}
+		if v.Secret != nil {
+			add(KindSecret, v.Secret.SecretName, RefSourceVolume, v.Secret.Optional)
+		}
+		if v.Projected == nil {
+			continue
+		}
+		for _, s := range v.Projected.Sources {
+			if s.ConfigMap != nil {
+				add(KindConfigMap, s.ConfigMap.Name, RefSourceProjected, s.ConfigMap.Optional)
+			}
+			if s.Secret != nil {
+				add(KindSecret, s.Secret.Name, RefSourceProjected, s.Secret.Optional)
+			}
+		}
+	}
+	for _, s := range spec.ImagePullSecrets {
+		add(KindSecret, s.Name, RefSourceImagePullSecret, nil)
+	}
+	return refs
+}
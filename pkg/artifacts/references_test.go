@@ -0,0 +1,117 @@
+package artifacts
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnalyzeConfigReferences(t *testing.T) {
+	artifactList := artifactsFromYAML(t, `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: api
+  namespace: app
+spec:
+  template:
+    spec:
+      imagePullSecrets:
+        - name: registry
+      initContainers:
+        - name: migrate
+          image: api:1.0
+          envFrom:
+            - secretRef:
+                name: db
+      containers:
+        - name: api
+          image: api:1.0
+          env:
+            - name: LEVEL
+              valueFrom:
+                configMapKeyRef:
+                  name: settings
+                  key: level
+            - name: PASSWORD
+              valueFrom:
+                secretKeyRef:
+                  name: db
+                  key: password
+            - name: FEATURE
+              valueFrom:
+                configMapKeyRef:
+                  name: features
+                  key: flag
+                  optional: true
+      volumes:
+        - name: config
+          configMap:
+            name: settings
+        - name: bundle
+          projected:
+            sources:
+              - secret:
+                  name: tls
+              - configMap:
+                  name: ca
+`, `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+  namespace: app
+`, `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: unused
+  namespace: app
+`, `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: ca
+  namespace: app
+`)
+	AnalyzeConfigReferences(artifactList, ConfigInventory{
+		KindSecret: {
+			{Kind: KindSecret, Namespace: "app", Name: "db"},
+			{Kind: KindSecret, Namespace: "app", Name: "registry"},
+		},
+	})
+
+	ref := func(kind, name, source string, optional, missing bool) ConfigReference {
+		return ConfigReference{
+			ObjectRef: ObjectRef{Kind: kind, Namespace: "app", Name: name},
+			Source:    source,
+			Optional:  optional,
+			Missing:   missing,
+			Dangling:  missing && !optional,
+		}
+	}
+	assert.Equal(t, []ConfigReference{
+		ref(KindConfigMap, "settings", RefSourceEnv, false, false),
+		ref(KindSecret, "db", RefSourceEnv, false, false),
+		ref(KindConfigMap, "features", RefSourceEnv, true, true),
+		ref(KindSecret, "db", RefSourceEnvFrom, false, false),
+		ref(KindConfigMap, "settings", RefSourceVolume, false, false),
+		ref(KindSecret, "tls", RefSourceProjected, false, true),
+		ref(KindConfigMap, "ca", RefSourceProjected, false, false),
+		ref(KindSecret, "registry", RefSourceImagePullSecret, false, false),
+	}, artifactList[0].ConfigReferences)
+
+	assert.Equal(t, map[ObjectRef][]ConfigReference{
+		{Kind: "Deployment", Namespace: "app", Name: "api"}: {
+			ref(KindSecret, "tls", RefSourceProjected, false, true),
+		},
+	}, DanglingConfigReferences(artifactList), "a missing optional reference is valid")
+	assert.False(t, artifactList[0].ConfigReferences[2].Dangling)
+
+	filtered := FilterReferencedConfig(artifactList)
+	names := make([]string, 0, len(filtered))
+	for _, a := range filtered {
+		names = append(names, a.Name)
+	}
+	assert.Equal(t, []string{"api", "settings", "ca"}, names)
+}
@@ -0,0 +1,230 @@
+package artifacts
+
+import (
+	_ "embed"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	KindCluster        = "Cluster"
+	KindNodeComponents = "NodeComponents"
+
+	kubeProxyComponent = "k8s.io/kube-proxy"
+)
+
+// Version skew findings
+const (
+	// SkewKubeletNewer kubelet is newer than the api server
+	SkewKubeletNewer = "KubeletNewerThanAPIServer"
+	// SkewKubeletTooOld kubelet is older than the api server beyond the supported skew
+	SkewKubeletTooOld = "KubeletTooOld"
+	// SkewMixedKubelets nodes run kubelets of several minor versions
+	SkewMixedKubelets = "MixedKubeletVersions"
+	// SkewKubeProxyNewer kube-proxy is newer than the api server
+	SkewKubeProxyNewer = "KubeProxyNewerThanAPIServer"
+	// SkewKubeProxyTooOld kube-proxy is older than the api server beyond the supported skew
+	SkewKubeProxyTooOld = "KubeProxyTooOld"
+)
+
+//go:embed releases.yaml
+var defaultReleaseCalendar []byte
+
+var minorVersionRegexp = regexp.MustCompile(`^v?(\d+)\.(\d+)`)
+
+// VersionSkew is a component version outside of the kubernetes version skew policy
+type VersionSkew struct {
+	Type             string
+	Component        string
+	Version          string
+	APIServerVersion string
+	Message          string
+}
+
+// SupportStatus is the upstream maintenance status of a kubernetes minor version
+type SupportStatus struct {
+	// Version is the minor version, e.g. 1.30
+	Version   string
+	EndOfLife time.Time
+	// Known is false when the release calendar doesn't have the version
+	Known bool
+	// CalendarOutdated is true when the version is newer than the release calendar,
+	// its support status is unknown until the calendar is updated
+	CalendarOutdated bool
+	// EndOfLifeReached is true when the version is no longer maintained upstream
+	EndOfLifeReached bool
+}
+
+// ReleaseCalendar holds the end of life of the kubernetes minor versions
+type ReleaseCalendar struct {
+	endOfLife map[string]time.Time
+	// the latest minor version of the calendar
+	latestMajor, latestMinor int
+}
+
+type releaseCalendarFile struct {
+	Releases []struct {
+		Version     string `json:"version"`
+		ReleaseDate string `json:"releaseDate"`
+		EndOfLife   string `json:"endOfLife"`
+	} `json:"releases"`
+}
+
+// DefaultReleaseCalendar returns the embedded release calendar
+func DefaultReleaseCalendar() *ReleaseCalendar {
+	calendar, err := LoadReleaseCalendar(defaultReleaseCalendar)
+	if err != nil {
+		panic(fmt.Sprintf("embedded release calendar: %v", err))
+	}
+	return calendar
+}
+
+// LoadReleaseCalendar parses a release calendar in the format of the embedded one
+func LoadReleaseCalendar(data []byte) (*ReleaseCalendar, error) {
+	var file releaseCalendarFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing release calendar: %w", err)
+	}
+	calendar := &ReleaseCalendar{endOfLife: make(map[string]time.Time)}
+	for _, r := range file.Releases {
+		endOfLife, err := time.Parse(time.DateOnly, r.EndOfLife)
+		if err != nil {
+			return nil, fmt.Errorf("parsing end of life of %s: %w", r.Version, err)
+		}
+		calendar.endOfLife[r.Version] = endOfLife
+		if major, minor, ok := minorVersion(r.Version); ok && (major > calendar.latestMajor ||
+			major == calendar.latestMajor && minor > calendar.latestMinor) {
+			calendar.latestMajor, calendar.latestMinor = major, minor
+		}
+	}
+	return calendar, nil
+}
+
+// Status returns the support status of the minor version of a kubernetes version
+func (c *ReleaseCalendar) Status(version string, now time.Time) *SupportStatus {
+	major, minor, ok := minorVersion(version)
+	if !ok {
+		return nil
+	}
+	status := &SupportStatus{Version: fmt.Sprintf("%d.%d", major, minor)}
+	if endOfLife, ok := c.endOfLife[status.Version]; ok {
+		status.Known = true
+		status.EndOfLife = endOfLife
+		status.EndOfLifeReached = !now.Before(endOfLife)
+	} else {
+		status.CalendarOutdated = major > c.latestMajor || major == c.latestMajor && minor > c.latestMinor
+	}
+	return status
+}
+
+// AnalyzeVersionSkew sets the version skew findings and support status of the
+// Cluster and NodeComponents artifacts of a BOM: the kubelet skew on the nodes,
+// mixed kubelets and kube-proxy skew on the cluster
+func AnalyzeVersionSkew(artifactList []*Artifact, calendar *ReleaseCalendar, now time.Time) {
+	var cluster *Artifact
+	var apiServerVersion string
+	nodes := make([]*Artifact, 0)
+	kubeProxies := make([]*Artifact, 0)
+	for _, a := range artifactList {
+		switch {
+		case a.Kind == KindCluster:
+			cluster = a
+			apiServerVersion = rawString(a.RawResource, "version")
+		case a.Kind == KindNodeComponents:
+			nodes = append(nodes, a)
+		case rawString(a.RawResource, "Name") == kubeProxyComponent:
+			kubeProxies = append(kubeProxies, a)
+		}
+	}
+
+	kubeletMinors := make([]string, 0)
+	for _, node := range nodes {
+		kubeletVersion := rawString(node.RawResource, "KubeletVersion")
+		node.Support = calendar.Status(kubeletVersion, now)
+		node.VersionSkew = nil
+		if skew := componentSkew("kubelet", kubeletVersion, apiServerVersion, SkewKubeletNewer, SkewKubeletTooOld); skew != nil {
+			node.VersionSkew = append(node.VersionSkew, *skew)
+		}
+		if major, minor, ok := minorVersion(kubeletVersion); ok {
+			if v := fmt.Sprintf("%d.%d", major, minor); !slices.Contains(kubeletMinors, v) {
+				kubeletMinors = append(kubeletMinors, v)
+			}
+		}
+	}
+	if cluster == nil {
+		return
+	}
+
+	cluster.Support = calendar.Status(apiServerVersion, now)
+	if cluster.Support != nil && cluster.Support.CalendarOutdated {
+		slog.Warn("The release calendar is outdated, the support status of the cluster version is unknown",
+			"version", apiServerVersion, "latest", fmt.Sprintf("%d.%d", calendar.latestMajor, calendar.latestMinor))
+	}
+	cluster.VersionSkew = nil
+	if len(kubeletMinors) > 1 {
+		slices.Sort(kubeletMinors)
+		cluster.VersionSkew = append(cluster.VersionSkew, VersionSkew{
+			Type:             SkewMixedKubelets,
+			Component:        "kubelet",
+			Version:          strings.Join(kubeletMinors, ", "),
+			APIServerVersion: apiServerVersion,
+			Message:          "nodes run kubelet minor versions " + strings.Join(kubeletMinors, ", "),
+		})
+	}
+	for _, proxy := range kubeProxies {
+		version := rawString(proxy.RawResource, "Version")
+		if skew := componentSkew("kube-proxy", version, apiServerVersion, SkewKubeProxyNewer, SkewKubeProxyTooOld); skew != nil {
+			if !slices.ContainsFunc(cluster.VersionSkew, func(s VersionSkew) bool { return *skew == s }) {
+				cluster.VersionSkew = append(cluster.VersionSkew, *skew)
+			}
+		}
+	}
+}
+
+// componentSkew checks a node component against the api server: it must not be
+// newer, and at most 3 minor versions older since 1.28, 2 before
+func componentSkew(component, version, apiServerVersion, newer, tooOld string) *VersionSkew {
+	major, minor, ok := minorVersion(version)
+	apiMajor, apiMinor, apiOK := minorVersion(apiServerVersion)
+	if !ok || !apiOK || major != apiMajor {
+		return nil
+	}
+	maxSkew := 3
+	if apiMinor < 28 {
+		maxSkew = 2
+	}
+	skew := VersionSkew{Component: component, Version: version, APIServerVersion: apiServerVersion}
+	switch {
+	case minor > apiMinor:
+		skew.Type = newer
+		skew.Message = fmt.Sprintf("%s %s is newer than api server %s", component, version, apiServerVersion)
+	case apiMinor-minor > maxSkew:
+		skew.Type = tooOld
+		skew.Message = fmt.Sprintf("%s %s is more than %d minor versions older than api server %s", component, version, maxSkew, apiServerVersion)
+	default:
+		return nil
+	}
+	return &skew
+}
+
+func minorVersion(version string) (int, int, bool) {
+	m := minorVersionRegexp.FindStringSubmatch(version)
+	if m == nil {
+		return 0, 0, false
+	}
+	major, _ := strconv.Atoi(m[1])
+	minor, _ := strconv.Atoi(m[2])
+	return major, minor, true
+}
+
+func rawString(raw map[string]interface{}, key string) string {
+	s, _ := raw[key].(string)
+	return s
+}
@@ -0,0 +1,155 @@
+package artifacts
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReleaseCalendarStatus(t *testing.T) {
+	now := time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC)
+	calendar := DefaultReleaseCalendar()
+
+	tests := []struct {
+		name    string
+		version string
+		want    *SupportStatus
+	}{
+		{
+			name:    "maintained",
+			version: "v1.32.3",
+			want:    &SupportStatus{Version: "1.32", EndOfLife: time.Date(2026, 2, 28, 0, 0, 0, 0, time.UTC), Known: true},
+		},
+		{
+			name:    "end of life",
+			version: "1.29.0-eks-a1b2c3",
+			want:    &SupportStatus{Version: "1.29", EndOfLife: time.Date(2025, 2, 28, 0, 0, 0, 0, time.UTC), Known: true, EndOfLifeReached: true},
+		},
+		{
+			name:    "latest in the calendar",
+			version: "v1.36.1",
+			want:    &SupportStatus{Version: "1.36", EndOfLife: time.Date(2027, 6, 28, 0, 0, 0, 0, time.UTC), Known: true},
+		},
+		{
+			name:    "newer than the calendar",
+			version: "1.99.0",
+			want:    &SupportStatus{Version: "1.99", CalendarOutdated: true},
+		},
+		{
+			name:    "older than the calendar",
+			version: "1.2.0",
+			want:    &SupportStatus{Version: "1.2"},
+		},
+		{
+			name:    "not a version",
+			version: "latest",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.want, calendar.Status(test.version, now))
+		})
+	}
+}
+
+func TestLoadReleaseCalendar(t *testing.T) {
+	calendar, err := LoadReleaseCalendar([]byte(`releases:
+  - version: "1.99"
+    endOfLife: 2030-01-31
+`))
+	require.NoError(t, err)
+	status := calendar.Status("1.99.1", time.Date(2030, 2, 1, 0, 0, 0, 0, time.UTC))
+	assert.True(t, status.Known)
+	assert.True(t, status.EndOfLifeReached)
+
+	_, err = LoadReleaseCalendar([]byte(`releases:
+  - version: "1.99"
+    endOfLife: soon
+`))
+	assert.ErrorContains(t, err, "parsing end of life of 1.99")
+}
+
+func TestAnalyzeVersionSkew(t *testing.T) {
+	now := time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name             string
+		apiServerVersion string
+		kubelets         []string
+		kubeProxies      []string
+		wantNodeSkew     [][]string
+		wantClusterSkew  []string
+	}{
+		{
+			name:             "within the skew policy",
+			apiServerVersion: "1.31.2",
+			kubelets:         []string{"v1.31.2", "v1.31.1"},
+			kubeProxies:      []string{"1.31.2"},
+			wantNodeSkew:     [][]string{nil, nil},
+		},
+		{
+			name:             "kubelet newer than the api server",
+			apiServerVersion: "1.30.4",
+			kubelets:         []string{"v1.31.0"},
+			wantNodeSkew:     [][]string{{SkewKubeletNewer}},
+		},
+		{
+			name:             "kubelet too old",
+			apiServerVersion: "1.32.1",
+			kubelets:         []string{"v1.28.9", "v1.29.1"},
+			wantNodeSkew:     [][]string{{SkewKubeletTooOld}, nil},
+			wantClusterSkew:  []string{SkewMixedKubelets},
+		},
+		{
+			name:             "kubelet too old before 1.28",
+			apiServerVersion: "1.27.3",
+			kubelets:         []string{"v1.24.0"},
+			wantNodeSkew:     [][]string{{SkewKubeletTooOld}},
+		},
+		{
+			name:             "kube-proxy skew reported once",
+			apiServerVersion: "1.30.0",
+			kubelets:         []string{"v1.30.0"},
+			kubeProxies:      []string{"1.31.0", "1.31.0", "1.25.0"},
+			wantNodeSkew:     [][]string{nil},
+			wantClusterSkew:  []string{SkewKubeProxyNewer, SkewKubeProxyTooOld},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cluster := &Artifact{Kind: KindCluster, RawResource: map[string]interface{}{"version": test.apiServerVersion}}
+			artifactList := []*Artifact{cluster}
+			var nodes []*Artifact
+			for _, kubelet := range test.kubelets {
+				node := &Artifact{Kind: KindNodeComponents, RawResource: map[string]interface{}{"KubeletVersion": kubelet}}
+				nodes = append(nodes, node)
+				artifactList = append(artifactList, node)
+			}
+			for _, proxy := range test.kubeProxies {
+				artifactList = append(artifactList, &Artifact{
+					Kind:        "ControlPlaneComponents",
+					RawResource: map[string]interface{}{"Name": kubeProxyComponent, "Version": proxy},
+				})
+			}
+
+			AnalyzeVersionSkew(artifactList, DefaultReleaseCalendar(), now)
+
+			require.NotNil(t, cluster.Support)
+			assert.Equal(t, test.wantClusterSkew, skewTypes(cluster.VersionSkew))
+			for i, node := range nodes {
+				require.NotNil(t, node.Support)
+				assert.Equal(t, test.wantNodeSkew[i], skewTypes(node.VersionSkew))
+			}
+		})
+	}
+}
+
+func skewTypes(skews []VersionSkew) []string {
+	var types []string
+	for _, skew := range skews {
+		types = append(types, skew.Type)
+	}
+	return types
+}
@@ -0,0 +1,60 @@
+package artifacts
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/aquasecurity/trivy-kubernetes/pkg/k8s"
+)
+
+// ObjectRef identifies a kubernetes object related to an artifact
+type ObjectRef struct {
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+// IsWorkload returns true if the artifact kind runs pods
+func (a *Artifact) IsWorkload() bool {
+	switch a.Kind {
+	case k8s.KindPod, k8s.KindJob, k8s.KindCronJob, k8s.KindReplicaSet, k8s.KindReplicationController,
+		k8s.KindStatefulSet, k8s.KindDaemonSet, k8s.KindDeployment:
+		return true
+	}
+	return false
+}
+
+// PodLabels returns the labels of the pods run by a workload artifact
+func (a *Artifact) PodLabels() map[string]string {
+	if !a.IsWorkload() {
+		return nil
+	}
+	keys := []string{"metadata", "labels"}
+	if a.Kind != k8s.KindPod {
+		nestedKeys := getContainerNestedKeys(a.Kind)
+		keys = append(nestedKeys[:len(nestedKeys)-1], keys...)
+	}
+	labels, _, err := unstructured.NestedStringMap(a.RawResource, keys...)
+	if err != nil {
+		return nil
+	}
+	return labels
+}
+
+// PodSpec returns the pod spec of a workload artifact, nil if the artifact
+// is not a workload or the spec can't be decoded
+func (a *Artifact) PodSpec() *corev1.PodSpec {
+	if !a.IsWorkload() {
+		return nil
+	}
+	spec, found, err := unstructured.NestedMap(a.RawResource, getContainerNestedKeys(a.Kind)...)
+	if err != nil || !found {
+		return nil
+	}
+	var podSpec corev1.PodSpec
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(spec, &podSpec); err != nil {
+		return nil
+	}
+	return &podSpec
+}
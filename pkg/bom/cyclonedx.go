@@ -0,0 +1,238 @@
+package bom
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	cdx "github.com/CycloneDX/cyclonedx-go"
+	"github.com/google/uuid"
+)
+
+// PropertyNamespace prefixes the names of the kubernetes properties of the
+// CycloneDX components, e.g. aquasecurity:trivy-kubernetes:Namespace
+const PropertyNamespace = "aquasecurity:trivy-kubernetes"
+
+// Kubernetes properties of the CycloneDX components, besides the BOM properties
+// which keep their name
+const (
+	// PropertyNamespaceName is the namespace of a component
+	PropertyNamespaceName = "Namespace"
+	// PropertyResource is a kubernetes object deployed by a component, as kind/namespace/name
+	PropertyResource = "Resource"
+	// PropertyImageID is the image a node has pulled, as registry/repository:tag
+	PropertyImageID = "ImageID"
+)
+
+const (
+	kubeletComponent = "k8s.io/kubelet"
+	toolName         = "trivy-kubernetes"
+	toolVendor       = "aquasecurity"
+)
+
+// EncodeCycloneDX writes the result as a CycloneDX 1.5 or 1.6 BOM, in JSON or XML
+func EncodeCycloneDX(w io.Writer, r *Result, format cdx.BOMFileFormat, specVersion cdx.SpecVersion) error {
+	if specVersion != cdx.SpecVersion1_5 && specVersion != cdx.SpecVersion1_6 {
+		return fmt.Errorf("unsupported CycloneDX spec version %s", specVersion)
+	}
+	if err := cdx.NewBOMEncoder(w, format).SetPretty(true).EncodeVersion(ToCycloneDX(r), specVersion); err != nil {
+		return fmt.Errorf("encoding CycloneDX BOM: %w", err)
+	}
+	return nil
+}
+
+// ToCycloneDX converts the result to a CycloneDX BOM: the cluster is the metadata
+// component, the control plane components, their containers and the nodes are
+// components, and the dependencies are the dependency graph of the result
+func ToCycloneDX(r *Result) *cdx.BOM {
+	b := cdx.NewBOM()
+	b.SerialNumber = uuid.New().URN()
+	clusterRef := r.ID
+	b.Metadata = &cdx.Metadata{
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Tools: &cdx.ToolsChoice{
+			Components: &[]cdx.Component{
+				{Type: cdx.ComponentTypeApplication, Group: toolVendor, Name: toolName},
+			},
+		},
+		Component: &cdx.Component{
+			BOMRef:     clusterRef,
+			Type:       cdx.ComponentTypePlatform,
+			Name:       r.ID,
+			Version:    r.Version,
+			Properties: nonEmpty(properties(r.Properties)),
+		},
+	}
+
+	c := &cycloneDXConverter{containers: make(map[string]cdx.Component)}
+	for i, ref := range ComponentRefs(r) {
+		c.addComponent(ref, r.Components[i])
+	}
+	for _, node := range r.NodesInfo {
+		c.addNode(node)
+	}
+
+	containerRefs := make([]string, 0, len(c.containers))
+	for ref := range c.containers {
+		containerRefs = append(containerRefs, ref)
+	}
+	sort.Strings(containerRefs)
+	for _, ref := range containerRefs {
+		c.components = append(c.components, c.containers[ref])
+	}
+
+	b.Components = &c.components
+
+	graph := r.Dependencies
+	if graph == nil {
+		graph = BuildDependencies(r)
+	}
+	dependencies := make([]cdx.Dependency, 0, len(graph))
+	for _, d := range graph {
+		dependsOn := append([]string{}, d.DependsOn...)
+		dependencies = append(dependencies, cdx.Dependency{Ref: d.Ref, Dependencies: &dependsOn})
+	}
+	b.Dependencies = &dependencies
+	return b
+}
+
+type cycloneDXConverter struct {
+	components []cdx.Component
+	containers map[string]cdx.Component
+}
+
+func (c *cycloneDXConverter) addComponent(ref string, component Component) {
+	props := properties(component.Properties)
+	if component.Namespace != "" {
+		props = appendProperty(props, PropertyNamespaceName, component.Namespace)
+	}
+	for _, resource := range component.Resources {
+		props = appendProperty(props, PropertyResource, resourceID(resource))
+	}
+	c.components = append(c.components, cdx.Component{
+		BOMRef:     ref,
+		Type:       cdx.ComponentTypeApplication,
+		Name:       component.Name,
+		Version:    component.Version,
+		PackageURL: component.PURL,
+		Properties: nonEmpty(props),
+	})
+	for _, container := range component.Containers {
+		c.addContainer(container)
+	}
+}
+
+// addContainer adds the container image once, whichever components run it
+func (c *cycloneDXConverter) addContainer(container Container) {
+	ref := ContainerRef(container)
+	if _, ok := c.containers[ref]; ok {
+		return
+	}
+	component := cdx.Component{
+		BOMRef:     ref,
+		Type:       cdx.ComponentTypeContainer,
+		Name:       container.Registry + "/" + container.Repository,
+		Version:    container.Version,
+		PackageURL: container.PURL,
+	}
+	if container.Digest != "" {
+		component.Hashes = &[]cdx.Hash{{Algorithm: cdx.HashAlgoSHA256, Value: container.Digest}}
+	}
+	c.containers[ref] = component
+}
+
+// addNode adds the node with its kubelet, container runtime and operating system
+func (c *cycloneDXConverter) addNode(node NodeInfo) {
+	subComponents := []cdx.Component{
+		{
+			BOMRef:     KubeletRef(node.NodeName),
+			Type:       cdx.ComponentTypeApplication,
+			Name:       kubeletComponent,
+			Version:    node.KubeletVersion,
+			PackageURL: node.KubeletPURL,
+		},
+	}
+	if node.ContainerRuntimeVersion != "" {
+		runtime, version, ok := strings.Cut(node.ContainerRuntimeVersion, "://")
+		if !ok {
+			runtime, version = node.ContainerRuntimeVersion, ""
+		}
+		subComponents = append(subComponents, cdx.Component{
+			BOMRef:     ContainerRuntimeRef(node.NodeName),
+			Type:       cdx.ComponentTypeApplication,
+			Name:       runtime,
+			Version:    version,
+			PackageURL: node.ContainerRuntimePURL,
+		})
+	}
+	if node.OsImage != "" {
+		subComponents = append(subComponents, cdx.Component{
+			BOMRef:     OSRef(node.NodeName),
+			Type:       cdx.ComponentTypeOS,
+			Name:       node.OsImage,
+			PackageURL: node.OsPURL,
+		})
+	}
+
+	props := properties(node.Properties)
+	for _, image := range node.Images {
+		props = appendProperty(props, PropertyImageID, image)
+	}
+	c.components = append(c.components, cdx.Component{
+		BOMRef:     NodeRef(node.NodeName),
+		Type:       cdx.ComponentTypePlatform,
+		Name:       node.NodeName,
+		Properties: nonEmpty(props),
+		Components: &subComponents,
+	})
+}
+
+// properties returns the BOM properties in the kubernetes namespace, sorted by name
+func properties(props map[string]string) *[]cdx.Property {
+	keys := make([]string, 0, len(props))
+	for k := range props {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	result := make([]cdx.Property, 0, len(props))
+	for _, k := range keys {
+		if props[k] != "" {
+			result = append(result, cdx.Property{Name: PropertyNamespace + ":" + k, Value: props[k]})
+		}
+	}
+	return &result
+}
+
+func appendProperty(props *[]cdx.Property, name, value string) *[]cdx.Property {
+	result := append(*props, cdx.Property{Name: PropertyNamespace + ":" + name, Value: value})
+	return &result
+}
+
+func nonEmpty(props *[]cdx.Property) *[]cdx.Property {
+	if len(*props) == 0 {
+		return nil
+	}
+	return props
+}
+
+func resourceID(r Resource) string {
+	if r.Namespace == "" {
+		return r.Kind + "/" + r.Name
+	}
+	return r.Kind + "/" + r.Namespace + "/" + r.Name
+}
+
+func imageID(c Container) string {
+	return c.Registry + "/" + c.Repository + ":" + c.Version
+}
+
+func contains(refs []string, ref string) bool {
+	for _, r := range refs {
+		if r == ref {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,180 @@
+package bom
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	cdx "github.com/CycloneDX/cyclonedx-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+const apiServerDigest = "18e61c783b41758dd391ab901366ec3546b26fae00eef7e223d1f94da808e02f"
+
+func testResult() *Result {
+	apiServer := Container{
+		ID:         "kube-apiserver:v1.31.0",
+		Version:    "v1.31.0",
+		Repository: "kube-apiserver",
+		Registry:   "registry.k8s.io",
+		Digest:     apiServerDigest,
+		PURL:       "pkg:oci/kube-apiserver@sha256%3A" + apiServerDigest + "?repository_url=registry.k8s.io%2Fkube-apiserver&tag=v1.31.0",
+	}
+	return &Result{
+		ID:         "k8s.io/kubernetes",
+		Type:       "Cluster",
+		Version:    "1.31.0",
+		Properties: map[string]string{"Name": "kind-kind", "Type": "cluster"},
+		Components: []Component{
+			{
+				Namespace:  "kube-system",
+				Name:       "k8s.io/apiserver",
+				Version:    "1.31.0",
+				Properties: map[string]string{"Name": "kube-apiserver-node-1", "Type": "controlPlane"},
+				Containers: []Container{apiServer},
+				PURL:       "pkg:k8s/k8s.io/apiserver@1.31.0",
+			},
+			{
+				Namespace:  "kube-system",
+				Name:       "k8s.io/apiserver",
+				Version:    "1.31.0",
+				Properties: map[string]string{"Name": "kube-apiserver-node-2", "Type": "controlPlane"},
+				Containers: []Container{apiServer},
+				PURL:       "pkg:k8s/k8s.io/apiserver@1.31.0",
+			},
+			{
+				Namespace:  "apps",
+				Name:       "nginx",
+				Version:    "15.1.0",
+				Properties: map[string]string{"Name": "web", "Type": "helmRelease"},
+				Resources:  []Resource{{Kind: "Deployment", Namespace: "apps", Name: "web-nginx"}, {Kind: "ClusterRole", Name: "web-nginx"}},
+			},
+		},
+		NodesInfo: []NodeInfo{
+			{
+				NodeName:                "node-1",
+				KubeletVersion:          "1.31.0",
+				ContainerRuntimeVersion: "containerd://1.7.18",
+				OsImage:                 "Debian GNU/Linux 12 (bookworm)",
+				Properties:              map[string]string{"NodeRole": "master", "Architecture": "amd64"},
+				Images:                  []string{"registry.k8s.io/kube-apiserver:v1.31.0"},
+				KubeletPURL:             "pkg:k8s/k8s.io/kubelet@1.31.0",
+				ContainerRuntimePURL:    "pkg:golang/github.com/containerd/containerd@v1.7.18",
+				OsPURL:                  "pkg:generic/debian@12",
+			},
+		},
+	}
+}
+
+func TestEncodeCycloneDX(t *testing.T) {
+	tests := []struct {
+		name        string
+		specVersion cdx.SpecVersion
+		schema      string
+	}{
+		{name: "1.5", specVersion: cdx.SpecVersion1_5, schema: "bom-1.5.schema.json"},
+		{name: "1.6", specVersion: cdx.SpecVersion1_6, schema: "bom-1.6.schema.json"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var jsonBOM bytes.Buffer
+			require.NoError(t, EncodeCycloneDX(&jsonBOM, testResult(), cdx.BOMFileFormatJSON, test.specVersion))
+
+			result, err := cycloneDXSchema(t, test.schema).Validate(gojsonschema.NewBytesLoader(jsonBOM.Bytes()))
+			require.NoError(t, err)
+			assert.Empty(t, result.Errors())
+
+			var xmlBOM bytes.Buffer
+			require.NoError(t, EncodeCycloneDX(&xmlBOM, testResult(), cdx.BOMFileFormatXML, test.specVersion))
+			assert.Contains(t, xmlBOM.String(), `xmlns="http://cyclonedx.org/schema/bom/`+test.specVersion.String()+`"`)
+
+			fromJSON, fromXML := new(cdx.BOM), new(cdx.BOM)
+			require.NoError(t, cdx.NewBOMDecoder(&jsonBOM, cdx.BOMFileFormatJSON).Decode(fromJSON))
+			require.NoError(t, cdx.NewBOMDecoder(&xmlBOM, cdx.BOMFileFormatXML).Decode(fromXML))
+			assert.Equal(t, test.specVersion, fromXML.SpecVersion)
+			assert.Equal(t, refs(fromJSON), refs(fromXML))
+		})
+	}
+
+	err := EncodeCycloneDX(&bytes.Buffer{}, testResult(), cdx.BOMFileFormatJSON, cdx.SpecVersion1_4)
+	assert.ErrorContains(t, err, "unsupported CycloneDX spec version 1.4")
+}
+
+func TestToCycloneDX(t *testing.T) {
+	b := ToCycloneDX(testResult())
+
+	assert.True(t, strings.HasPrefix(b.SerialNumber, "urn:uuid:"))
+	assert.Equal(t, "k8s.io/kubernetes", b.Metadata.Component.Name)
+	assert.Equal(t, cdx.ComponentTypePlatform, b.Metadata.Component.Type)
+	assert.Equal(t, []cdx.Property{
+		{Name: "aquasecurity:trivy-kubernetes:Name", Value: "kind-kind"},
+		{Name: "aquasecurity:trivy-kubernetes:Type", Value: "cluster"},
+	}, *b.Metadata.Component.Properties)
+
+	components := make(map[string]cdx.Component)
+	for _, c := range *b.Components {
+		components[c.BOMRef] = c
+	}
+	containerRef := "container:registry.k8s.io/kube-apiserver@sha256:" + apiServerDigest
+	require.Contains(t, components, containerRef)
+	assert.Equal(t, cdx.ComponentTypeContainer, components[containerRef].Type)
+	assert.Equal(t, []cdx.Hash{{Algorithm: cdx.HashAlgoSHA256, Value: apiServerDigest}}, *components[containerRef].Hashes)
+	assert.Equal(t, testResult().Components[0].Containers[0].PURL, components[containerRef].PackageURL)
+	assert.Len(t, *b.Components, 5, "the container shared by two components is listed once")
+	assert.Equal(t, "pkg:k8s/k8s.io/apiserver@1.31.0", components["component:kube-system/kube-apiserver-node-1"].PackageURL)
+
+	require.Contains(t, components, "component:apps/web")
+	assert.Contains(t, *components["component:apps/web"].Properties,
+		cdx.Property{Name: "aquasecurity:trivy-kubernetes:Resource", Value: "ClusterRole/web-nginx"})
+
+	node := components["node:node-1"]
+	assert.Equal(t, cdx.ComponentTypePlatform, node.Type)
+	require.Len(t, *node.Components, 3)
+	assert.Equal(t, cdx.Component{
+		BOMRef:     "node:node-1/runtime",
+		Type:       cdx.ComponentTypeApplication,
+		Name:       "containerd",
+		Version:    "1.7.18",
+		PackageURL: "pkg:golang/github.com/containerd/containerd@v1.7.18",
+	}, (*node.Components)[1])
+	assert.Equal(t, "pkg:generic/debian@12", (*node.Components)[2].PackageURL)
+
+	dependencies := make(map[string][]string)
+	for _, d := range *b.Dependencies {
+		dependencies[d.Ref] = *d.Dependencies
+	}
+	assert.Equal(t, []string{"component:kube-system/kube-apiserver-node-1", "component:kube-system/kube-apiserver-node-2", "component:apps/web", "node:node-1"}, dependencies["k8s.io/kubernetes"])
+	assert.Equal(t, []string{containerRef}, dependencies["component:kube-system/kube-apiserver-node-1"])
+	assert.Equal(t, []string{"node:node-1/kubelet", "node:node-1/runtime", "node:node-1/os", containerRef}, dependencies["node:node-1"])
+}
+
+// cycloneDXSchema compiles the CycloneDX schema with the schemas it references,
+// which are resolved from testdata instead of cyclonedx.org
+func cycloneDXSchema(t *testing.T, name string) *gojsonschema.Schema {
+	loader := gojsonschema.NewSchemaLoader()
+	for _, ref := range []string{"spdx.schema.json", "jsf-0.82.schema.json"} {
+		data, err := os.ReadFile(filepath.Join("testdata", "schema", ref))
+		require.NoError(t, err)
+		require.NoError(t, loader.AddSchema("http://cyclonedx.org/schema/"+ref, gojsonschema.NewBytesLoader(data)))
+	}
+	data, err := os.ReadFile(filepath.Join("testdata", "schema", name))
+	require.NoError(t, err)
+	schema, err := loader.Compile(gojsonschema.NewBytesLoader(data))
+	require.NoError(t, err)
+	return schema
+}
+
+func refs(b *cdx.BOM) []string {
+	var result []string
+	for _, c := range *b.Components {
+		result = append(result, c.BOMRef)
+	}
+	for _, d := range *b.Dependencies {
+		result = append(result, d.Ref)
+	}
+	return result
+}
@@ -0,0 +1,426 @@
+package bom
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"unicode"
+)
+
+// DiffFormat is a serialization of BOM diffs
+type DiffFormat string
+
+const (
+	DiffFormatJSON  DiffFormat = "json"
+	DiffFormatTable DiffFormat = "table"
+)
+
+// Directions of a version change, a change is neutral when the versions are
+// not comparable
+const (
+	ChangeUpgraded   = "upgraded"
+	ChangeDowngraded = "downgraded"
+	ChangeChanged    = "changed"
+)
+
+// the Type property of the components built from helm releases
+const helmReleaseType = "helmRelease"
+
+// Diff is the structured difference between two BOMs of a cluster
+type Diff struct {
+	ClusterVersion    *Change           `json:"clusterVersion,omitempty"`
+	AddedComponents   []Component       `json:"addedComponents,omitempty"`
+	RemovedComponents []Component       `json:"removedComponents,omitempty"`
+	ChangedComponents []ComponentChange `json:"changedComponents,omitempty"`
+	ContainerChanges  []ContainerChange `json:"containerChanges,omitempty"`
+	AddedNodes        []NodeInfo        `json:"addedNodes,omitempty"`
+	RemovedNodes      []NodeInfo        `json:"removedNodes,omitempty"`
+	NodeChanges       []NodeChange      `json:"nodeChanges,omitempty"`
+}
+
+// Change is a value before and after, the direction is set on version changes
+type Change struct {
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Direction string `json:"direction,omitempty"`
+}
+
+// ComponentChange is a version change of a component, the versions of its
+// replicas are joined when they differ. Release is the name of a helm release
+type ComponentChange struct {
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	Release   string `json:"release,omitempty"`
+	Version   Change `json:"version"`
+}
+
+// ContainerChange is a change of the images of a component repository, the
+// images are tag@sha256:digest and empty for an added or removed container
+type ContainerChange struct {
+	Namespace  string `json:"namespace,omitempty"`
+	Component  string `json:"component"`
+	Repository string `json:"repository"`
+	Image      Change `json:"image"`
+}
+
+// NodeChange is a change of the kubelet, container runtime or OS of a node
+type NodeChange struct {
+	NodeName         string  `json:"nodeName"`
+	KubeletVersion   *Change `json:"kubeletVersion,omitempty"`
+	ContainerRuntime *Change `json:"containerRuntime,omitempty"`
+	OsImage          *Change `json:"osImage,omitempty"`
+}
+
+// Decode reads a BOM result encoded in JSON
+func Decode(r io.Reader) (*Result, error) {
+	result := &Result{}
+	if err := json.NewDecoder(r).Decode(result); err != nil {
+		return nil, fmt.Errorf("decoding BOM: %w", err)
+	}
+	return result, nil
+}
+
+// Compare returns the changes from the before to the after BOM. The components
+// are matched by namespace and name, and helm releases by release name too,
+// their replicas are compared as one, and the nodes are matched by name
+func Compare(before, after *Result) *Diff {
+	d := &Diff{}
+	if before.Version != after.Version {
+		d.ClusterVersion = versionChange(before.Version, after.Version)
+	}
+
+	beforeComponents, afterComponents := groupComponents(before.Components), groupComponents(after.Components)
+	for _, key := range sortedKeys(afterComponents) {
+		if _, ok := beforeComponents[key]; !ok {
+			d.AddedComponents = append(d.AddedComponents, afterComponents[key]...)
+		}
+	}
+	for _, key := range sortedKeys(beforeComponents) {
+		from := beforeComponents[key]
+		to, ok := afterComponents[key]
+		if !ok {
+			d.RemovedComponents = append(d.RemovedComponents, from...)
+			continue
+		}
+		if fromVersion, toVersion := componentVersions(from), componentVersions(to); fromVersion != toVersion {
+			d.ChangedComponents = append(d.ChangedComponents, ComponentChange{
+				Namespace: from[0].Namespace,
+				Name:      from[0].Name,
+				Release:   helmRelease(from[0]),
+				Version:   *versionChange(fromVersion, toVersion),
+			})
+		}
+		d.ContainerChanges = append(d.ContainerChanges, containerChanges(from, to)...)
+	}
+
+	beforeNodes, afterNodes := nodesByName(before.NodesInfo), nodesByName(after.NodesInfo)
+	for _, node := range after.NodesInfo {
+		if _, ok := beforeNodes[node.NodeName]; !ok {
+			d.AddedNodes = append(d.AddedNodes, node)
+		}
+	}
+	for _, from := range before.NodesInfo {
+		to, ok := afterNodes[from.NodeName]
+		if !ok {
+			d.RemovedNodes = append(d.RemovedNodes, from)
+			continue
+		}
+		change := NodeChange{
+			NodeName:         from.NodeName,
+			KubeletVersion:   changedVersion(from.KubeletVersion, to.KubeletVersion),
+			ContainerRuntime: changedVersion(from.ContainerRuntimeVersion, to.ContainerRuntimeVersion),
+			OsImage:          changedVersion(from.OsImage, to.OsImage),
+		}
+		if change.KubeletVersion != nil || change.ContainerRuntime != nil || change.OsImage != nil {
+			d.NodeChanges = append(d.NodeChanges, change)
+		}
+	}
+	return d
+}
+
+// Empty reports whether the BOMs are the same
+func (d *Diff) Empty() bool {
+	return d.ClusterVersion == nil && len(d.AddedComponents) == 0 && len(d.RemovedComponents) == 0 &&
+		len(d.ChangedComponents) == 0 && len(d.ContainerChanges) == 0 && len(d.AddedNodes) == 0 &&
+		len(d.RemovedNodes) == 0 && len(d.NodeChanges) == 0
+}
+
+// EncodeDiff writes the diff in JSON or as a human-readable table
+func EncodeDiff(w io.Writer, d *Diff, format DiffFormat) error {
+	switch format {
+	case DiffFormatJSON:
+		e := json.NewEncoder(w)
+		e.SetIndent("", "  ")
+		if err := e.Encode(d); err != nil {
+			return fmt.Errorf("encoding BOM diff: %w", err)
+		}
+		return nil
+	case DiffFormatTable:
+		return writeDiffTable(w, d)
+	default:
+		return fmt.Errorf("unsupported BOM diff format %q", format)
+	}
+}
+
+func writeDiffTable(w io.Writer, d *Diff) error {
+	if d.Empty() {
+		_, err := fmt.Fprintln(w, "No changes")
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	row := func(columns ...string) {
+		for i, c := range columns {
+			if c == "" {
+				columns[i] = "-"
+			}
+		}
+		fmt.Fprintln(tw, strings.Join(columns, "\t"))
+	}
+	fmt.Fprintln(tw, "CHANGE\tKIND\tNAMESPACE\tNAME\tFROM\tTO")
+	if d.ClusterVersion != nil {
+		row(d.ClusterVersion.Direction, "Cluster", "", "", d.ClusterVersion.From, d.ClusterVersion.To)
+	}
+	for _, c := range d.AddedComponents {
+		row("added", "Component", c.Namespace, componentName(c), "", c.Version)
+	}
+	for _, c := range d.RemovedComponents {
+		row("removed", "Component", c.Namespace, componentName(c), c.Version, "")
+	}
+	for _, c := range d.ChangedComponents {
+		name := c.Name
+		if c.Release != "" {
+			name += " (" + c.Release + ")"
+		}
+		row(c.Version.Direction, "Component", c.Namespace, name, c.Version.From, c.Version.To)
+	}
+	for _, c := range d.ContainerChanges {
+		change := ChangeChanged
+		switch {
+		case c.Image.From == "":
+			change = "added"
+		case c.Image.To == "":
+			change = "removed"
+		}
+		row(change, "Container", c.Namespace, c.Component+" "+c.Repository, shortImage(c.Image.From), shortImage(c.Image.To))
+	}
+	for _, n := range d.AddedNodes {
+		row("added", "Node", "", n.NodeName, "", n.KubeletVersion)
+	}
+	for _, n := range d.RemovedNodes {
+		row("removed", "Node", "", n.NodeName, n.KubeletVersion, "")
+	}
+	for _, n := range d.NodeChanges {
+		if n.KubeletVersion != nil {
+			row(n.KubeletVersion.Direction, "Kubelet", "", n.NodeName, n.KubeletVersion.From, n.KubeletVersion.To)
+		}
+		if n.ContainerRuntime != nil {
+			row(n.ContainerRuntime.Direction, "ContainerRuntime", "", n.NodeName, n.ContainerRuntime.From, n.ContainerRuntime.To)
+		}
+		if n.OsImage != nil {
+			row(n.OsImage.Direction, "OperatingSystem", "", n.NodeName, n.OsImage.From, n.OsImage.To)
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return fmt.Errorf("writing BOM diff table: %w", err)
+	}
+	return nil
+}
+
+// groupComponents groups the replicas of the components, the releases of a
+// helm chart in a namespace are distinct components
+func groupComponents(components []Component) map[string][]Component {
+	groups := make(map[string][]Component)
+	for _, c := range components {
+		key := c.Namespace + "/" + c.Name
+		if release := helmRelease(c); release != "" {
+			key += "/" + release
+		}
+		groups[key] = append(groups[key], c)
+	}
+	return groups
+}
+
+// helmRelease returns the release name of a component built from a helm release
+func helmRelease(c Component) string {
+	if c.Properties["Type"] != helmReleaseType {
+		return ""
+	}
+	return c.Properties["Name"]
+}
+
+// componentVersions returns the sorted distinct versions of the replicas
+func componentVersions(replicas []Component) string {
+	var versions []string
+	for _, c := range replicas {
+		if !contains(versions, c.Version) {
+			versions = append(versions, c.Version)
+		}
+	}
+	sort.Strings(versions)
+	return strings.Join(versions, ", ")
+}
+
+// containerChanges compares the images of the component replicas by repository,
+// the image of a repository found on one side only is empty on the other
+func containerChanges(from, to []Component) []ContainerChange {
+	fromImages, toImages := imagesByRepository(from), imagesByRepository(to)
+	repositories := make(map[string]struct{})
+	for repository := range fromImages {
+		repositories[repository] = struct{}{}
+	}
+	for repository := range toImages {
+		repositories[repository] = struct{}{}
+	}
+	var changes []ContainerChange
+	for _, repository := range sortedKeys(repositories) {
+		fromImage, toImage := strings.Join(fromImages[repository], ", "), strings.Join(toImages[repository], ", ")
+		if fromImage == toImage {
+			continue
+		}
+		changes = append(changes, ContainerChange{
+			Namespace:  from[0].Namespace,
+			Component:  from[0].Name,
+			Repository: repository,
+			Image:      Change{From: fromImage, To: toImage},
+		})
+	}
+	return changes
+}
+
+func imagesByRepository(replicas []Component) map[string][]string {
+	images := make(map[string][]string)
+	for _, c := range replicas {
+		for _, container := range c.Containers {
+			repository := strings.TrimPrefix(container.Registry+"/"+container.Repository, "/")
+			image := container.Version
+			if container.Digest != "" {
+				image += "@sha256:" + container.Digest
+			}
+			if !contains(images[repository], image) {
+				images[repository] = append(images[repository], image)
+			}
+		}
+	}
+	for _, i := range images {
+		sort.Strings(i)
+	}
+	return images
+}
+
+func nodesByName(nodes []NodeInfo) map[string]NodeInfo {
+	result := make(map[string]NodeInfo, len(nodes))
+	for _, n := range nodes {
+		result[n.NodeName] = n
+	}
+	return result
+}
+
+func changedVersion(from, to string) *Change {
+	if from == to {
+		return nil
+	}
+	return versionChange(from, to)
+}
+
+func versionChange(from, to string) *Change {
+	direction := ChangeChanged
+	if cmp, ok := compareVersions(from, to); ok && cmp < 0 {
+		direction = ChangeUpgraded
+	} else if ok && cmp > 0 {
+		direction = ChangeDowngraded
+	}
+	return &Change{From: from, To: to, Direction: direction}
+}
+
+// compareVersions compares the dotted numbers of versions such as v1.31.0,
+// containerd://1.7.20 or Ubuntu 22.04.4 LTS, a pre-release suffix is lower
+// than none. The versions are not comparable when the text around the numbers
+// differs otherwise
+func compareVersions(a, b string) (int, bool) {
+	aPrefix, aNumbers, aSuffix, ok := splitVersion(a)
+	if !ok {
+		return 0, false
+	}
+	bPrefix, bNumbers, bSuffix, ok := splitVersion(b)
+	if !ok || aPrefix != bPrefix {
+		return 0, false
+	}
+	for i := 0; i < max(len(aNumbers), len(bNumbers)); i++ {
+		var x, y int
+		if i < len(aNumbers) {
+			x = aNumbers[i]
+		}
+		if i < len(bNumbers) {
+			y = bNumbers[i]
+		}
+		if x != y {
+			if x < y {
+				return -1, true
+			}
+			return 1, true
+		}
+	}
+	switch {
+	case aSuffix == bSuffix:
+		return 0, true
+	case strings.HasPrefix(aSuffix, "-") && bSuffix == "":
+		return -1, true
+	case aSuffix == "" && strings.HasPrefix(bSuffix, "-"):
+		return 1, true
+	}
+	return 0, false
+}
+
+// splitVersion splits a version around its first dotted numbers, the joined
+// versions of mixed replicas are not split
+func splitVersion(version string) (string, []int, string, bool) {
+	start := strings.IndexFunc(version, unicode.IsDigit)
+	if start < 0 || strings.Contains(version, ", ") {
+		return "", nil, "", false
+	}
+	prefix, rest := strings.TrimSuffix(version[:start], "v"), version[start:]
+	end := strings.IndexFunc(rest, func(r rune) bool { return !unicode.IsDigit(r) && r != '.' })
+	if end < 0 {
+		end = len(rest)
+	}
+	var numbers []int
+	for _, part := range strings.Split(strings.TrimSuffix(rest[:end], "."), ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return "", nil, "", false
+		}
+		numbers = append(numbers, n)
+	}
+	return prefix, numbers, rest[end:], true
+}
+
+func componentName(c Component) string {
+	if name := c.Properties["Name"]; name != "" && name != c.Name {
+		return c.Name + " (" + name + ")"
+	}
+	return c.Name
+}
+
+// shortImage abbreviates the digests of the images to 12 characters
+func shortImage(images string) string {
+	parts := strings.Split(images, ", ")
+	for i, image := range parts {
+		if tag, digest, ok := strings.Cut(image, "@sha256:"); ok && len(digest) > 12 {
+			parts[i] = tag + "@" + digest[:12]
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
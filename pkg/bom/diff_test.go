@@ -0,0 +1,236 @@
+package bom
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const upgradedAPIServerDigest = "a1b2c3d4e5f60718293a4b5c6d7e8f90a1b2c3d4e5f60718293a4b5c6d7e8f90"
+
+func upgradedResult() *Result {
+	r := testResult()
+	r.Version = "1.31.1"
+	for i := range r.Components[:2] {
+		r.Components[i].Version = "1.31.1"
+		r.Components[i].Containers = []Container{{
+			ID:         "kube-apiserver:v1.31.1",
+			Version:    "v1.31.1",
+			Repository: "kube-apiserver",
+			Registry:   "registry.k8s.io",
+			Digest:     upgradedAPIServerDigest,
+		}}
+	}
+	r.Components[2] = Component{
+		Namespace:  "kube-system",
+		Name:       "k8s.io/coredns",
+		Version:    "1.11.1",
+		Properties: map[string]string{"Name": "coredns-5d78c9869d-x7k2p", "Type": "addon"},
+	}
+	r.NodesInfo[0].KubeletVersion = "1.31.1"
+	r.NodesInfo[0].ContainerRuntimeVersion = "containerd://1.7.20"
+	r.NodesInfo = append(r.NodesInfo, NodeInfo{NodeName: "node-2", KubeletVersion: "1.31.1"})
+	return r
+}
+
+func TestCompare(t *testing.T) {
+	d := Compare(testResult(), upgradedResult())
+
+	assert.Equal(t, &Change{From: "1.31.0", To: "1.31.1", Direction: ChangeUpgraded}, d.ClusterVersion)
+	require.Len(t, d.AddedComponents, 1)
+	assert.Equal(t, "k8s.io/coredns", d.AddedComponents[0].Name)
+	require.Len(t, d.RemovedComponents, 1)
+	assert.Equal(t, "nginx", d.RemovedComponents[0].Name)
+	assert.Equal(t, []ComponentChange{
+		{Namespace: "kube-system", Name: "k8s.io/apiserver", Version: Change{From: "1.31.0", To: "1.31.1", Direction: ChangeUpgraded}},
+	}, d.ChangedComponents, "the replicas are compared as one component")
+	assert.Equal(t, []ContainerChange{
+		{
+			Namespace:  "kube-system",
+			Component:  "k8s.io/apiserver",
+			Repository: "registry.k8s.io/kube-apiserver",
+			Image:      Change{From: "v1.31.0@sha256:" + apiServerDigest, To: "v1.31.1@sha256:" + upgradedAPIServerDigest},
+		},
+	}, d.ContainerChanges)
+	require.Len(t, d.AddedNodes, 1)
+	assert.Equal(t, "node-2", d.AddedNodes[0].NodeName)
+	assert.Empty(t, d.RemovedNodes)
+	assert.Equal(t, []NodeChange{
+		{
+			NodeName:         "node-1",
+			KubeletVersion:   &Change{From: "1.31.0", To: "1.31.1", Direction: ChangeUpgraded},
+			ContainerRuntime: &Change{From: "containerd://1.7.18", To: "containerd://1.7.20", Direction: ChangeUpgraded},
+		},
+	}, d.NodeChanges)
+	assert.False(t, d.Empty())
+
+	assert.True(t, Compare(testResult(), testResult()).Empty())
+}
+
+func TestCompareMixedReplicas(t *testing.T) {
+	after := testResult()
+	after.Components[1].Version = "1.31.1"
+	after.Components[1].Containers = []Container{{Version: "v1.31.1", Repository: "kube-apiserver", Registry: "registry.k8s.io", Digest: upgradedAPIServerDigest}}
+
+	d := Compare(testResult(), after)
+	assert.Equal(t, []ComponentChange{
+		{Namespace: "kube-system", Name: "k8s.io/apiserver", Version: Change{From: "1.31.0", To: "1.31.0, 1.31.1", Direction: ChangeChanged}},
+	}, d.ChangedComponents)
+	require.Len(t, d.ContainerChanges, 1)
+	assert.Equal(t, "v1.31.0@sha256:"+apiServerDigest+", v1.31.1@sha256:"+upgradedAPIServerDigest, d.ContainerChanges[0].Image.To)
+}
+
+func TestCompareContainerRepositories(t *testing.T) {
+	after := testResult()
+	for i := range after.Components[:2] {
+		after.Components[i].Containers = []Container{
+			{Version: "v1.31.0", Repository: "kube-apiserver", Registry: "k8s.gcr.io", Digest: apiServerDigest},
+			{Version: "1.0", Repository: "acme/audit-forwarder", Registry: "ghcr.io"},
+		}
+	}
+
+	d := Compare(testResult(), after)
+	assert.Empty(t, d.ChangedComponents)
+	assert.Equal(t, []ContainerChange{
+		{
+			Namespace:  "kube-system",
+			Component:  "k8s.io/apiserver",
+			Repository: "ghcr.io/acme/audit-forwarder",
+			Image:      Change{To: "1.0"},
+		},
+		{
+			Namespace:  "kube-system",
+			Component:  "k8s.io/apiserver",
+			Repository: "k8s.gcr.io/kube-apiserver",
+			Image:      Change{To: "v1.31.0@sha256:" + apiServerDigest},
+		},
+		{
+			Namespace:  "kube-system",
+			Component:  "k8s.io/apiserver",
+			Repository: "registry.k8s.io/kube-apiserver",
+			Image:      Change{From: "v1.31.0@sha256:" + apiServerDigest},
+		},
+	}, d.ContainerChanges, "a registry move removes a repository and adds another")
+
+	var b bytes.Buffer
+	require.NoError(t, EncodeDiff(&b, d, DiffFormatTable))
+	assert.Contains(t, b.String(), "added    Container  kube-system  k8s.io/apiserver k8s.gcr.io/kube-apiserver")
+	assert.Contains(t, b.String(), "removed  Container  kube-system  k8s.io/apiserver registry.k8s.io/kube-apiserver")
+}
+
+func TestCompareDowngrade(t *testing.T) {
+	after := testResult()
+	after.Version = "1.30.4"
+	after.NodesInfo[0].KubeletVersion = "1.30.4"
+	after.NodesInfo[0].OsImage = "Flatcar Container Linux 3815.2.5"
+
+	d := Compare(testResult(), after)
+	assert.Equal(t, ChangeDowngraded, d.ClusterVersion.Direction)
+	require.Len(t, d.NodeChanges, 1)
+	assert.Equal(t, ChangeDowngraded, d.NodeChanges[0].KubeletVersion.Direction)
+	assert.Equal(t, ChangeChanged, d.NodeChanges[0].OsImage.Direction, "another distribution is not comparable")
+
+	var b bytes.Buffer
+	require.NoError(t, EncodeDiff(&b, d, DiffFormatTable))
+	assert.Contains(t, b.String(), "downgraded  Cluster")
+	assert.Contains(t, b.String(), "downgraded  Kubelet")
+}
+
+func TestCompareHelmReleases(t *testing.T) {
+	release := func(name, version string) Component {
+		return Component{
+			Namespace:  "apps",
+			Name:       "nginx",
+			Version:    version,
+			Properties: map[string]string{"Name": name, "Type": helmReleaseType},
+		}
+	}
+	before := &Result{Components: []Component{release("web", "15.1.0"), release("admin", "15.1.0")}}
+	after := &Result{Components: []Component{release("web", "15.2.0"), release("api", "15.1.0")}}
+
+	d := Compare(before, after)
+	assert.Equal(t, []ComponentChange{
+		{Namespace: "apps", Name: "nginx", Release: "web", Version: Change{From: "15.1.0", To: "15.2.0", Direction: ChangeUpgraded}},
+	}, d.ChangedComponents, "the releases of a chart are not replicas")
+	require.Len(t, d.AddedComponents, 1)
+	assert.Equal(t, "api", d.AddedComponents[0].Properties["Name"])
+	require.Len(t, d.RemovedComponents, 1)
+	assert.Equal(t, "admin", d.RemovedComponents[0].Properties["Name"])
+
+	var b bytes.Buffer
+	require.NoError(t, EncodeDiff(&b, d, DiffFormatTable))
+	assert.Contains(t, b.String(), "nginx (web)")
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+		ok   bool
+	}{
+		{a: "1.31.0", b: "1.31.1", want: -1, ok: true},
+		{a: "v1.31.10", b: "1.31.9", want: 1, ok: true},
+		{a: "1.31", b: "1.31.0", want: 0, ok: true},
+		{a: "v1.31.0-eks-a737599", b: "v1.31.1-eks-a737599", want: -1, ok: true},
+		{a: "v1.32.0-rc.1", b: "v1.32.0", want: -1, ok: true},
+		{a: "containerd://1.7.20", b: "containerd://1.7.18", want: 1, ok: true},
+		{a: "Ubuntu 22.04.4 LTS", b: "Ubuntu 24.04 LTS", want: -1, ok: true},
+		{a: "containerd://1.7.20", b: "cri-o://1.30.0"},
+		{a: "1.31.0", b: "1.31.0, 1.31.1"},
+		{a: "latest", b: "1.0.0"},
+	}
+	for _, test := range tests {
+		t.Run(test.a+" "+test.b, func(t *testing.T) {
+			got, ok := compareVersions(test.a, test.b)
+			assert.Equal(t, test.ok, ok)
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestEncodeDiff(t *testing.T) {
+	d := Compare(testResult(), upgradedResult())
+
+	var b bytes.Buffer
+	require.NoError(t, EncodeDiff(&b, d, DiffFormatJSON))
+	decoded := &Diff{}
+	require.NoError(t, json.Unmarshal(b.Bytes(), decoded))
+	assert.Equal(t, d, decoded)
+
+	b.Reset()
+	require.NoError(t, EncodeDiff(&b, d, DiffFormatTable))
+	assert.Equal(t, strings.Join([]string{
+		"CHANGE    KIND              NAMESPACE    NAME                                             FROM                  TO",
+		"upgraded  Cluster           -            -                                                1.31.0                1.31.1",
+		"added     Component         kube-system  k8s.io/coredns (coredns-5d78c9869d-x7k2p)        -                     1.11.1",
+		"removed   Component         apps         nginx (web)                                      15.1.0                -",
+		"upgraded  Component         kube-system  k8s.io/apiserver                                 1.31.0                1.31.1",
+		"changed   Container         kube-system  k8s.io/apiserver registry.k8s.io/kube-apiserver  v1.31.0@18e61c783b41  v1.31.1@a1b2c3d4e5f6",
+		"added     Node              -            node-2                                           -                     1.31.1",
+		"upgraded  Kubelet           -            node-1                                           1.31.0                1.31.1",
+		"upgraded  ContainerRuntime  -            node-1                                           containerd://1.7.18   containerd://1.7.20",
+		"",
+	}, "\n"), b.String())
+
+	b.Reset()
+	require.NoError(t, EncodeDiff(&b, Compare(testResult(), testResult()), DiffFormatTable))
+	assert.Equal(t, "No changes\n", b.String())
+
+	err := EncodeDiff(&bytes.Buffer{}, d, "yaml")
+	assert.ErrorContains(t, err, `unsupported BOM diff format "yaml"`)
+}
+
+func TestDecode(t *testing.T) {
+	data, err := json.Marshal(testResult())
+	require.NoError(t, err)
+	r, err := Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, testResult(), r)
+
+	_, err = Decode(strings.NewReader("{"))
+	assert.ErrorContains(t, err, "decoding BOM")
+}
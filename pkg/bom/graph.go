@@ -0,0 +1,166 @@
+package bom
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Reference prefixes of the dependency graph elements, the cluster is referenced
+// by the BOM name
+const (
+	NodeRefPrefix      = "node:"
+	ComponentRefPrefix = "component:"
+	ContainerRefPrefix = "container:"
+)
+
+// Dependency is an edge list of the dependency graph: the element referenced by
+// Ref depends on the elements referenced by DependsOn
+type Dependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn"`
+}
+
+// NodeRef returns the reference of a node
+func NodeRef(nodeName string) string {
+	return NodeRefPrefix + nodeName
+}
+
+// KubeletRef returns the reference of the kubelet of a node
+func KubeletRef(nodeName string) string {
+	return NodeRef(nodeName) + "/kubelet"
+}
+
+// ContainerRuntimeRef returns the reference of the container runtime of a node
+func ContainerRuntimeRef(nodeName string) string {
+	return NodeRef(nodeName) + "/runtime"
+}
+
+// OSRef returns the reference of the operating system of a node
+func OSRef(nodeName string) string {
+	return NodeRef(nodeName) + "/os"
+}
+
+// ContainerRef returns the reference of a container image, by digest when known
+func ContainerRef(c Container) string {
+	if c.Digest != "" {
+		return ContainerRefPrefix + c.Registry + "/" + c.Repository + "@sha256:" + c.Digest
+	}
+	return ContainerRefPrefix + imageID(c)
+}
+
+// ComponentRefs returns the references of the components of the result, by
+// index: components sharing a namespace and name get a #n suffix
+func ComponentRefs(r *Result) []string {
+	seen := make(map[string]int)
+	refs := make([]string, 0, len(r.Components))
+	for _, component := range r.Components {
+		name := component.Properties["Name"]
+		if name == "" {
+			name = component.Name
+		}
+		ref := ComponentRefPrefix + strings.TrimPrefix(component.Namespace+"/"+name, "/")
+		seen[ref]++
+		if n := seen[ref]; n > 1 {
+			ref = fmt.Sprintf("%s#%d", ref, n)
+		}
+		refs = append(refs, ref)
+	}
+	return refs
+}
+
+// BuildDependencies returns the dependency graph of the result: the cluster
+// depends on its components and nodes, the components on their containers, and
+// the nodes on their kubelet, container runtime and operating system, on the
+// components scheduled on them and on the images they have pulled
+func BuildDependencies(r *Result) []Dependency {
+	componentRefs := ComponentRefs(r)
+	var dependencies []Dependency
+	index := make(map[string]int)
+	add := func(ref string, dependsOn ...string) {
+		i, ok := index[ref]
+		if !ok {
+			index[ref] = len(dependencies)
+			dependencies = append(dependencies, Dependency{Ref: ref, DependsOn: []string{}})
+			i = len(dependencies) - 1
+		}
+		for _, d := range dependsOn {
+			if !contains(dependencies[i].DependsOn, d) {
+				dependencies[i].DependsOn = append(dependencies[i].DependsOn, d)
+			}
+		}
+	}
+
+	add(r.ID)
+	images := make(map[string]string)
+	for i, component := range r.Components {
+		add(r.ID, componentRefs[i])
+		add(componentRefs[i])
+		for _, container := range component.Containers {
+			containerRef := ContainerRef(container)
+			images[imageID(container)] = containerRef
+			add(componentRefs[i], containerRef)
+		}
+	}
+	for _, node := range r.NodesInfo {
+		nodeRef := NodeRef(node.NodeName)
+		add(r.ID, nodeRef)
+		add(nodeRef, KubeletRef(node.NodeName))
+		if node.ContainerRuntimeVersion != "" {
+			add(nodeRef, ContainerRuntimeRef(node.NodeName))
+		}
+		if node.OsImage != "" {
+			add(nodeRef, OSRef(node.NodeName))
+		}
+		for i, component := range r.Components {
+			if component.NodeName == node.NodeName {
+				add(nodeRef, componentRefs[i])
+			}
+		}
+		var nodeImages []string
+		for _, image := range node.Images {
+			if containerRef, ok := images[image]; ok {
+				nodeImages = append(nodeImages, containerRef)
+			}
+		}
+		sort.Strings(nodeImages)
+		add(nodeRef, nodeImages...)
+	}
+	for _, d := range append([]Dependency(nil), dependencies...) {
+		for _, ref := range d.DependsOn {
+			add(ref)
+		}
+	}
+	return dependencies
+}
+
+// Dependents returns the references of the elements depending, directly or
+// transitively, on the referenced element, e.g. the nodes and components
+// running a container image
+func (r *Result) Dependents(ref string) []string {
+	dependencies := r.Dependencies
+	if dependencies == nil {
+		dependencies = BuildDependencies(r)
+	}
+	dependents := make(map[string][]string)
+	for _, d := range dependencies {
+		for _, dependsOn := range d.DependsOn {
+			dependents[dependsOn] = append(dependents[dependsOn], d.Ref)
+		}
+	}
+	var result []string
+	seen := map[string]bool{ref: true}
+	queue := []string{ref}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, dependent := range dependents[current] {
+			if !seen[dependent] {
+				seen[dependent] = true
+				result = append(result, dependent)
+				queue = append(queue, dependent)
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,58 @@
+package bom
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildDependencies(t *testing.T) {
+	r := testResult()
+	r.Components[0].NodeName = "node-1"
+	r.Components = append(r.Components, r.Components[2])
+
+	containerRef := "container:registry.k8s.io/kube-apiserver@sha256:" + apiServerDigest
+	assert.Equal(t, []Dependency{
+		{Ref: "k8s.io/kubernetes", DependsOn: []string{
+			"component:kube-system/kube-apiserver-node-1",
+			"component:kube-system/kube-apiserver-node-2",
+			"component:apps/web",
+			"component:apps/web#2",
+			"node:node-1",
+		}},
+		{Ref: "component:kube-system/kube-apiserver-node-1", DependsOn: []string{containerRef}},
+		{Ref: "component:kube-system/kube-apiserver-node-2", DependsOn: []string{containerRef}},
+		{Ref: "component:apps/web", DependsOn: []string{}},
+		{Ref: "component:apps/web#2", DependsOn: []string{}},
+		{Ref: "node:node-1", DependsOn: []string{
+			"node:node-1/kubelet",
+			"node:node-1/runtime",
+			"node:node-1/os",
+			"component:kube-system/kube-apiserver-node-1",
+			containerRef,
+		}},
+		{Ref: containerRef, DependsOn: []string{}},
+		{Ref: "node:node-1/kubelet", DependsOn: []string{}},
+		{Ref: "node:node-1/runtime", DependsOn: []string{}},
+		{Ref: "node:node-1/os", DependsOn: []string{}},
+	}, BuildDependencies(r))
+}
+
+func TestDependents(t *testing.T) {
+	r := testResult()
+	r.NodesInfo = append(r.NodesInfo, NodeInfo{NodeName: "node-2"})
+	r.Components[1].NodeName = "node-2"
+	r.Dependencies = BuildDependencies(r)
+
+	var nodes []string
+	for _, ref := range r.Dependents("container:registry.k8s.io/kube-apiserver@sha256:" + apiServerDigest) {
+		if strings.HasPrefix(ref, NodeRefPrefix) {
+			nodes = append(nodes, ref)
+		}
+	}
+	assert.ElementsMatch(t, []string{"node:node-1", "node:node-2"}, nodes,
+		"node-1 has pulled the image, node-2 runs a component using it")
+	assert.Equal(t, []string{"k8s.io/kubernetes"}, r.Dependents("node:node-1"))
+	assert.Empty(t, r.Dependents("k8s.io/kubernetes"))
+}
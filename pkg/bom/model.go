@@ -7,6 +7,8 @@ type Result struct {
 	Components []Component `json:"components,omitempty"`
 	NodesInfo  []NodeInfo  `json:"nodesInfo,omitempty"`
 	Properties map[string]string
+	// Dependencies is the dependency graph of the cluster, see BuildDependencies
+	Dependencies []Dependency `json:"dependencies,omitempty"`
 }
 
 type Component struct {
@@ -15,6 +17,18 @@ type Component struct {
 	Version    string
 	Properties map[string]string
 	Containers []Container
+	Resources  []Resource `json:",omitempty"`
+	// PURL is set for the components of a known upstream organization
+	PURL string `json:",omitempty"`
+	// NodeName is the node the component pod is scheduled on
+	NodeName string `json:",omitempty"`
+}
+
+// Resource identifies a kubernetes object deployed by a component
+type Resource struct {
+	Kind      string
+	Namespace string `json:",omitempty"`
+	Name      string
 }
 
 type Container struct {
@@ -23,6 +37,7 @@ type Container struct {
 	Repository string
 	Registry   string
 	Digest     string
+	PURL       string `json:",omitempty"`
 }
 
 type NodeInfo struct {
@@ -32,6 +47,9 @@ type NodeInfo struct {
 	OsImage                 string
 	Properties              map[string]string
 	Images                  []string
+	KubeletPURL             string `json:",omitempty"`
+	ContainerRuntimePURL    string `json:",omitempty"`
+	OsPURL                  string `json:",omitempty"`
 }
 
 type ClusterInfo struct {
@@ -0,0 +1,111 @@
+package bom
+
+import (
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/package-url/packageurl-go"
+)
+
+// TypeKubernetes is the purl type of the kubernetes core components, e.g.
+// pkg:k8s/k8s.io/apiserver@1.29.1
+const TypeKubernetes = "k8s"
+
+const kubernetesOrg = "k8s.io"
+
+// containerRuntimeModules are the go modules of the container runtimes, by the
+// runtime name reported by the kubelet
+var containerRuntimeModules = map[string]string{
+	"containerd": "github.com/containerd/containerd",
+	"cri-o":      "github.com/cri-o/cri-o",
+}
+
+// osImagePatterns map the node OS images to a distribution and its version
+var osImagePatterns = []struct {
+	distribution string
+	pattern      *regexp.Regexp
+}{
+	{"ubuntu", regexp.MustCompile(`^Ubuntu (\d+\.\d+(?:\.\d+)?)`)},
+	{"debian", regexp.MustCompile(`^Debian GNU/Linux (\d+)`)},
+	{"amazon", regexp.MustCompile(`^Amazon Linux (\d+)`)},
+	{"rhcos", regexp.MustCompile(`^Red Hat Enterprise Linux CoreOS (\S+)`)},
+	{"rhel", regexp.MustCompile(`^Red Hat Enterprise Linux (\d+(?:\.\d+)?)`)},
+	{"bottlerocket", regexp.MustCompile(`^Bottlerocket OS (\S+)`)},
+	{"cos", regexp.MustCompile(`^Container-Optimized OS from Google()`)},
+	{"flatcar", regexp.MustCompile(`^Flatcar Container Linux by Kinvolk (\S+)`)},
+	{"talos", regexp.MustCompile(`^Talos \(v?([^)]+)\)`)},
+	{"azurelinux", regexp.MustCompile(`^(?:Azure Linux|CBL-Mariner/Linux) ?(\S*)`)},
+}
+
+// ContainerPURL returns the pkg:oci purl of a container image, versioned by its
+// digest as the oci purl type defines, with its repository and tag as qualifiers
+func ContainerPURL(c Container) string {
+	if c.Repository == "" {
+		return ""
+	}
+	repositoryURL := c.Repository
+	if c.Registry != "" {
+		repositoryURL = c.Registry + "/" + c.Repository
+	}
+	qualifiers := map[string]string{"repository_url": repositoryURL}
+	if c.Version != "" {
+		qualifiers["tag"] = c.Version
+	}
+	version := ""
+	if c.Digest != "" {
+		version = "sha256:" + c.Digest
+	}
+	return purl(packageurl.TypeOCI, "", path.Base(c.Repository), version, qualifiers)
+}
+
+// ComponentPURL returns the purl of a component of an upstream organization: a
+// pkg:k8s purl for the kubernetes core components, a pkg:golang purl for the
+// other organizations, nothing without an organization
+func ComponentPURL(org, name, version string) string {
+	if org == "" || name == "" {
+		return ""
+	}
+	if org == kubernetesOrg {
+		return purl(TypeKubernetes, org, name, version, nil)
+	}
+	module := org + "/" + name
+	if version != "" && !strings.HasPrefix(version, "v") {
+		version = "v" + version
+	}
+	return purl(packageurl.TypeGolang, path.Dir(module), path.Base(module), version, nil)
+}
+
+// ContainerRuntimePURL returns the pkg:golang purl of a container runtime from
+// its version reported by the kubelet, e.g. containerd://1.7.18
+func ContainerRuntimePURL(runtimeVersion string) string {
+	runtime, version, ok := strings.Cut(runtimeVersion, "://")
+	module, known := containerRuntimeModules[runtime]
+	if !ok || !known {
+		return ""
+	}
+	version = "v" + strings.TrimPrefix(version, "v")
+	// containerd 2 is the github.com/containerd/containerd/v2 module
+	if major, _, _ := strings.Cut(version, "."); runtime == "containerd" && major != "v0" && major != "v1" {
+		module += "/" + major
+	}
+	return purl(packageurl.TypeGolang, path.Dir(module), path.Base(module), version, nil)
+}
+
+// OSPURL returns the pkg:generic purl of the distribution of a node OS image
+func OSPURL(osImage string) string {
+	for _, p := range osImagePatterns {
+		if m := p.pattern.FindStringSubmatch(osImage); m != nil {
+			return purl(packageurl.TypeGeneric, "", p.distribution, m[1], nil)
+		}
+	}
+	return ""
+}
+
+func purl(purlType, namespace, name, version string, qualifiers map[string]string) string {
+	var q packageurl.Qualifiers
+	if len(qualifiers) > 0 {
+		q = packageurl.QualifiersFromMap(qualifiers)
+	}
+	return packageurl.NewPackageURL(purlType, namespace, name, version, q, "").ToString()
+}
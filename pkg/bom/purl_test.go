@@ -0,0 +1,99 @@
+package bom
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContainerPURL(t *testing.T) {
+	tests := []struct {
+		name      string
+		container Container
+		want      string
+	}{
+		{
+			name: "registry, tag and digest",
+			container: Container{
+				Registry:   "registry.k8s.io",
+				Repository: "coredns/coredns",
+				Version:    "v1.11.1",
+				Digest:     "1eeb4c7316bacb1d4c8ead65571cd92dd21e27359f0d4917f1a5822a73b75db1",
+			},
+			want: "pkg:oci/coredns@sha256%3A1eeb4c7316bacb1d4c8ead65571cd92dd21e27359f0d4917f1a5822a73b75db1?repository_url=registry.k8s.io%2Fcoredns%2Fcoredns&tag=v1.11.1",
+		},
+		{
+			name:      "registry port without digest",
+			container: Container{Registry: "myregistry.com:5000", Repository: "project/image", Version: "v1.0.0"},
+			want:      "pkg:oci/image?repository_url=myregistry.com%3A5000%2Fproject%2Fimage&tag=v1.0.0",
+		},
+		{
+			name:      "no repository",
+			container: Container{Registry: "gcr.io", Version: "v1.0.0"},
+			want:      "",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.want, ContainerPURL(test.container))
+		})
+	}
+}
+
+func TestComponentPURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		org     string
+		module  string
+		version string
+		want    string
+	}{
+		{name: "kubernetes core component", org: "k8s.io", module: "apiserver", version: "1.29.1", want: "pkg:k8s/k8s.io/apiserver@1.29.1"},
+		{name: "go module", org: "go.etcd.io", module: "etcd/v3", version: "3.5.15-0", want: "pkg:golang/go.etcd.io/etcd/v3@v3.5.15-0"},
+		{name: "go module with v prefix", org: "github.com/coredns", module: "coredns", version: "v1.11.1", want: "pkg:golang/github.com/coredns/coredns@v1.11.1"},
+		{name: "no organization", module: "nginx", version: "1.25.0", want: ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.want, ComponentPURL(test.org, test.module, test.version))
+		})
+	}
+}
+
+func TestContainerRuntimePURL(t *testing.T) {
+	tests := []struct {
+		runtimeVersion string
+		want           string
+	}{
+		{runtimeVersion: "containerd://1.7.18", want: "pkg:golang/github.com/containerd/containerd@v1.7.18"},
+		{runtimeVersion: "containerd://2.0.0", want: "pkg:golang/github.com/containerd/containerd/v2@v2.0.0"},
+		{runtimeVersion: "cri-o://1.30.2", want: "pkg:golang/github.com/cri-o/cri-o@v1.30.2"},
+		{runtimeVersion: "docker://20.10.7", want: ""},
+		{runtimeVersion: "containerd", want: ""},
+	}
+	for _, test := range tests {
+		t.Run(test.runtimeVersion, func(t *testing.T) {
+			assert.Equal(t, test.want, ContainerRuntimePURL(test.runtimeVersion))
+		})
+	}
+}
+
+func TestOSPURL(t *testing.T) {
+	tests := []struct {
+		osImage string
+		want    string
+	}{
+		{osImage: "Ubuntu 22.04.4 LTS", want: "pkg:generic/ubuntu@22.04.4"},
+		{osImage: "Debian GNU/Linux 12 (bookworm)", want: "pkg:generic/debian@12"},
+		{osImage: "Amazon Linux 2", want: "pkg:generic/amazon@2"},
+		{osImage: "Red Hat Enterprise Linux CoreOS 416.94.202407081958-0", want: "pkg:generic/rhcos@416.94.202407081958-0"},
+		{osImage: "Bottlerocket OS 1.20.3 (aws-k8s-1.29)", want: "pkg:generic/bottlerocket@1.20.3"},
+		{osImage: "Talos (v1.7.5)", want: "pkg:generic/talos@1.7.5"},
+		{osImage: "Windows Server 2022 Datacenter", want: ""},
+	}
+	for _, test := range tests {
+		t.Run(test.osImage, func(t *testing.T) {
+			assert.Equal(t, test.want, OSPURL(test.osImage))
+		})
+	}
+}
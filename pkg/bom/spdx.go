@@ -0,0 +1,283 @@
+package bom
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	spdxjson "github.com/spdx/tools-golang/json"
+	"github.com/spdx/tools-golang/spdx/v2/common"
+	spdx "github.com/spdx/tools-golang/spdx/v2/v2_3"
+	"github.com/spdx/tools-golang/tagvalue"
+)
+
+// SPDXFormat is a serialization of SPDX documents
+type SPDXFormat string
+
+const (
+	SPDXFormatJSON     SPDXFormat = "json"
+	SPDXFormatTagValue SPDXFormat = "tag-value"
+)
+
+// SPDXNamespacePrefix prefixes the document namespaces, which end with a UUID
+// derived from the BOM: the same BOM always gets the same namespace
+const SPDXNamespacePrefix = "https://aquasecurity.github.io/trivy-kubernetes/spdx/"
+
+const (
+	spdxNoAssertion   = "NOASSERTION"
+	spdxDocumentID    = "DOCUMENT"
+	spdxAnnotatorType = "Tool"
+	spdxCreatorType   = "Tool"
+
+	purposeApplication = "APPLICATION"
+	purposeContainer   = "CONTAINER"
+	purposeOS          = "OPERATING-SYSTEM"
+	purposeOther       = "OTHER"
+)
+
+// EncodeSPDX writes the result as an SPDX 2.3 document, in JSON or tag-value
+func EncodeSPDX(w io.Writer, r *Result, format SPDXFormat) error {
+	doc, err := ToSPDX(r)
+	if err != nil {
+		return err
+	}
+	switch format {
+	case SPDXFormatJSON:
+		err = spdxjson.Write(doc, w, spdxjson.Indent("  "))
+	case SPDXFormatTagValue:
+		// tag-value has no package annotations, they are document annotations
+		// referring to the package
+		for _, pkg := range doc.Packages {
+			for i := range pkg.Annotations {
+				doc.Annotations = append(doc.Annotations, &pkg.Annotations[i])
+			}
+			pkg.Annotations = nil
+		}
+		err = tagvalue.Write(doc, w)
+	default:
+		return fmt.Errorf("unsupported SPDX format %q", format)
+	}
+	if err != nil {
+		return fmt.Errorf("encoding SPDX document: %w", err)
+	}
+	return nil
+}
+
+// ToSPDX converts the result to an SPDX document describing the cluster package:
+// it contains the components and the nodes, the components depend on their
+// container images, and the nodes contain their kubelet, container runtime,
+// operating system, the components scheduled on them and their images
+func ToSPDX(r *Result) (*spdx.Document, error) {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling BOM: %w", err)
+	}
+	name := r.ID
+	if clusterName := r.Properties["Name"]; clusterName != "" {
+		name = clusterName
+	}
+	c := &spdxConverter{
+		created:  time.Now().UTC().Format(time.RFC3339),
+		packages: make(map[common.ElementID]*spdx.Package),
+	}
+	doc := &spdx.Document{
+		SPDXVersion:       spdx.Version,
+		DataLicense:       spdx.DataLicense,
+		SPDXIdentifier:    spdxDocumentID,
+		DocumentName:      name,
+		DocumentNamespace: SPDXNamespacePrefix + namespaceName(name) + "-" + uuid.NewSHA1(uuid.NameSpaceURL, data).String(),
+		CreationInfo: &spdx.CreationInfo{
+			Creators: []common.Creator{{Creator: toolName, CreatorType: spdxCreatorType}},
+			Created:  c.created,
+		},
+	}
+
+	clusterID := c.addPackage("Cluster", r.ID, r.ID, r.Version, purposeOther, r.Properties)
+	c.relate(spdxDocumentID, common.TypeRelationshipDescribe, clusterID)
+	images := make(map[string]common.ElementID)
+	componentIDs := make([]common.ElementID, 0, len(r.Components))
+	for _, component := range r.Components {
+		key := component.Properties["Name"]
+		if key == "" {
+			key = component.Name
+		}
+		props := make(map[string]string)
+		for k, v := range component.Properties {
+			props[k] = v
+		}
+		if component.Namespace != "" {
+			props[PropertyNamespaceName] = component.Namespace
+		}
+		componentID := c.addPackage("Component", component.Namespace+"/"+key, component.Name, component.Version, purposeApplication, props)
+		c.setPURL(componentID, component.PURL)
+		c.relate(clusterID, common.TypeRelationshipContains, componentID)
+		componentIDs = append(componentIDs, componentID)
+		for _, container := range component.Containers {
+			imageRef := c.addImage(container)
+			images[imageID(container)] = imageRef
+			c.relate(componentID, common.TypeRelationshipDependsOn, imageRef)
+		}
+	}
+	for _, node := range r.NodesInfo {
+		nodeID := c.addPackage("Node", node.NodeName, node.NodeName, "", purposeOther, node.Properties)
+		c.relate(clusterID, common.TypeRelationshipContains, nodeID)
+		kubeletID := c.addPackage("Kubelet", node.NodeName, kubeletComponent, node.KubeletVersion, purposeApplication, nil)
+		c.setPURL(kubeletID, node.KubeletPURL)
+		c.relate(nodeID, common.TypeRelationshipContains, kubeletID)
+		if node.ContainerRuntimeVersion != "" {
+			runtime, version, ok := strings.Cut(node.ContainerRuntimeVersion, "://")
+			if !ok {
+				runtime, version = node.ContainerRuntimeVersion, ""
+			}
+			runtimeID := c.addPackage("ContainerRuntime", node.NodeName, runtime, version, purposeApplication, nil)
+			c.setPURL(runtimeID, node.ContainerRuntimePURL)
+			c.relate(nodeID, common.TypeRelationshipContains, runtimeID)
+			c.relate(runtimeID, common.TypeRelationshipRuntimeDependencyOf, kubeletID)
+		}
+		if node.OsImage != "" {
+			osID := c.addPackage("OperatingSystem", node.NodeName, node.OsImage, "", purposeOS, nil)
+			c.setPURL(osID, node.OsPURL)
+			c.relate(nodeID, common.TypeRelationshipContains, osID)
+		}
+		for i, component := range r.Components {
+			if component.NodeName == node.NodeName {
+				c.relate(nodeID, common.TypeRelationshipContains, componentIDs[i])
+			}
+		}
+		for _, image := range node.Images {
+			if imageRef, ok := images[image]; ok {
+				c.relate(nodeID, common.TypeRelationshipContains, imageRef)
+			}
+		}
+	}
+
+	doc.Packages = c.orderedPackages()
+	doc.Relationships = c.relationships
+	return doc, nil
+}
+
+type spdxConverter struct {
+	created       string
+	packages      map[common.ElementID]*spdx.Package
+	order         []common.ElementID
+	relationships []*spdx.Relationship
+	related       map[string]bool
+}
+
+// addPackage adds the package once, its identifier is derived from its kind and key
+func (c *spdxConverter) addPackage(kind, key, name, version, purpose string, props map[string]string) common.ElementID {
+	id := elementID(kind, key)
+	if _, ok := c.packages[id]; ok {
+		return id
+	}
+	c.packages[id] = &spdx.Package{
+		PackageName:               name,
+		PackageSPDXIdentifier:     id,
+		PackageVersion:            version,
+		PackageDownloadLocation:   spdxNoAssertion,
+		IsFilesAnalyzedTagPresent: true,
+		PackageLicenseConcluded:   spdxNoAssertion,
+		PackageLicenseDeclared:    spdxNoAssertion,
+		PackageCopyrightText:      spdxNoAssertion,
+		PrimaryPackagePurpose:     purpose,
+		Annotations:               c.annotations(id, props),
+	}
+	c.order = append(c.order, id)
+	return id
+}
+
+// addImage adds the container image once, with its digest as checksum
+func (c *spdxConverter) addImage(container Container) common.ElementID {
+	key := imageID(container)
+	if container.Digest != "" {
+		key = container.Registry + "/" + container.Repository + "@sha256:" + container.Digest
+	}
+	id := c.addPackage("ContainerImage", key, container.Registry+"/"+container.Repository, container.Version, purposeContainer, nil)
+	if pkg := c.packages[id]; container.Digest != "" && len(pkg.PackageChecksums) == 0 {
+		pkg.PackageChecksums = []common.Checksum{{Algorithm: common.SHA256, Value: container.Digest}}
+	}
+	c.setPURL(id, container.PURL)
+	return id
+}
+
+// setPURL adds the purl of the package as a package manager external reference
+func (c *spdxConverter) setPURL(id common.ElementID, purl string) {
+	pkg := c.packages[id]
+	if purl == "" || len(pkg.PackageExternalReferences) > 0 {
+		return
+	}
+	pkg.PackageExternalReferences = []*spdx.PackageExternalReference{
+		{Category: common.CategoryPackageManager, RefType: common.TypePackageManagerPURL, Locator: purl},
+	}
+}
+
+// annotations records the BOM properties, which SPDX has no field for
+func (c *spdxConverter) annotations(id common.ElementID, props map[string]string) []spdx.Annotation {
+	keys := make([]string, 0, len(props))
+	for k, v := range props {
+		if v != "" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	annotations := make([]spdx.Annotation, 0, len(keys))
+	for _, k := range keys {
+		annotations = append(annotations, spdx.Annotation{
+			Annotator:                common.Annotator{Annotator: toolName, AnnotatorType: spdxAnnotatorType},
+			AnnotationDate:           c.created,
+			AnnotationType:           "OTHER",
+			AnnotationSPDXIdentifier: common.MakeDocElementID("", string(id)),
+			AnnotationComment:        PropertyNamespace + ":" + k + ": " + props[k],
+		})
+	}
+	return annotations
+}
+
+func (c *spdxConverter) relate(a common.ElementID, relationship string, b common.ElementID) {
+	key := string(a) + " " + relationship + " " + string(b)
+	if c.related == nil {
+		c.related = make(map[string]bool)
+	}
+	if c.related[key] {
+		return
+	}
+	c.related[key] = true
+	c.relationships = append(c.relationships, &spdx.Relationship{
+		RefA:         common.MakeDocElementID("", string(a)),
+		RefB:         common.MakeDocElementID("", string(b)),
+		Relationship: relationship,
+	})
+}
+
+// orderedPackages returns the packages in the order they were added
+func (c *spdxConverter) orderedPackages() []*spdx.Package {
+	packages := make([]*spdx.Package, 0, len(c.order))
+	for _, id := range c.order {
+		packages = append(packages, c.packages[id])
+	}
+	return packages
+}
+
+// elementID returns an SPDX identifier, which only allows letters, digits, dots
+// and dashes, from a hash of the package key
+func elementID(kind, key string) common.ElementID {
+	hash := sha256.Sum256([]byte(key))
+	return common.ElementID(kind + "-" + hex.EncodeToString(hash[:8]))
+}
+
+// namespaceName keeps the characters of a name allowed in a document namespace path
+func namespaceName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-':
+			return r
+		}
+		return '-'
+	}, name)
+}
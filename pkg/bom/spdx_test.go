@@ -0,0 +1,134 @@
+package bom
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	spdxjson "github.com/spdx/tools-golang/json"
+	"github.com/spdx/tools-golang/spdx/v2/common"
+	spdx "github.com/spdx/tools-golang/spdx/v2/v2_3"
+	"github.com/spdx/tools-golang/tagvalue"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeSPDX(t *testing.T) {
+	tests := []struct {
+		name   string
+		format SPDXFormat
+		read   func(*bytes.Buffer) (*spdx.Document, error)
+	}{
+		{
+			name:   "json",
+			format: SPDXFormatJSON,
+			read: func(b *bytes.Buffer) (*spdx.Document, error) {
+				doc := &spdx.Document{}
+				return doc, spdxjson.ReadInto(b, doc)
+			},
+		},
+		{
+			name:   "tag-value",
+			format: SPDXFormatTagValue,
+			read: func(b *bytes.Buffer) (*spdx.Document, error) {
+				doc := &spdx.Document{}
+				return doc, tagvalue.ReadInto(b, doc)
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var b bytes.Buffer
+			require.NoError(t, EncodeSPDX(&b, testResult(), test.format))
+			doc, err := test.read(&b)
+			require.NoError(t, err)
+
+			assert.Equal(t, spdx.Version, doc.SPDXVersion)
+			assert.Equal(t, "kind-kind", doc.DocumentName)
+			assert.True(t, strings.HasPrefix(doc.DocumentNamespace, SPDXNamespacePrefix+"kind-kind-"))
+			assert.Len(t, doc.Packages, 9)
+			assert.Len(t, doc.Relationships, 12)
+
+			var checksums []common.Checksum
+			for _, pkg := range doc.Packages {
+				checksums = append(checksums, pkg.PackageChecksums...)
+			}
+			assert.Equal(t, []common.Checksum{{Algorithm: common.SHA256, Value: apiServerDigest}}, checksums)
+
+			var purls []string
+			for _, pkg := range doc.Packages {
+				for _, ref := range pkg.PackageExternalReferences {
+					assert.Equal(t, common.CategoryPackageManager, ref.Category)
+					assert.Equal(t, common.TypePackageManagerPURL, ref.RefType)
+					purls = append(purls, ref.Locator)
+				}
+			}
+			assert.ElementsMatch(t, []string{
+				"pkg:k8s/k8s.io/apiserver@1.31.0",
+				"pkg:k8s/k8s.io/apiserver@1.31.0",
+				"pkg:oci/kube-apiserver@sha256%3A" + apiServerDigest + "?repository_url=registry.k8s.io%2Fkube-apiserver&tag=v1.31.0",
+				"pkg:k8s/k8s.io/kubelet@1.31.0",
+				"pkg:golang/github.com/containerd/containerd@v1.7.18",
+				"pkg:generic/debian@12",
+			}, purls)
+		})
+	}
+
+	err := EncodeSPDX(&bytes.Buffer{}, testResult(), "rdf")
+	assert.ErrorContains(t, err, `unsupported SPDX format "rdf"`)
+}
+
+func TestToSPDX(t *testing.T) {
+	doc, err := ToSPDX(testResult())
+	require.NoError(t, err)
+
+	again, err := ToSPDX(testResult())
+	require.NoError(t, err)
+	assert.Equal(t, doc.DocumentNamespace, again.DocumentNamespace, "the namespace is derived from the BOM")
+	changed := testResult()
+	changed.Version = "1.31.1"
+	other, err := ToSPDX(changed)
+	require.NoError(t, err)
+	assert.NotEqual(t, doc.DocumentNamespace, other.DocumentNamespace)
+
+	names := make(map[common.ElementID]string)
+	for _, pkg := range doc.Packages {
+		names[pkg.PackageSPDXIdentifier] = pkg.PackageName
+	}
+	var relationships []string
+	for _, r := range doc.Relationships {
+		a := names[r.RefA.ElementRefID]
+		if r.RefA.ElementRefID == spdxDocumentID {
+			a = "DOCUMENT"
+		}
+		relationships = append(relationships, a+" "+r.Relationship+" "+names[r.RefB.ElementRefID])
+	}
+	assert.Equal(t, []string{
+		"DOCUMENT DESCRIBES k8s.io/kubernetes",
+		"k8s.io/kubernetes CONTAINS k8s.io/apiserver",
+		"k8s.io/apiserver DEPENDS_ON registry.k8s.io/kube-apiserver",
+		"k8s.io/kubernetes CONTAINS k8s.io/apiserver",
+		"k8s.io/apiserver DEPENDS_ON registry.k8s.io/kube-apiserver",
+		"k8s.io/kubernetes CONTAINS nginx",
+		"k8s.io/kubernetes CONTAINS node-1",
+		"node-1 CONTAINS k8s.io/kubelet",
+		"node-1 CONTAINS containerd",
+		"containerd RUNTIME_DEPENDENCY_OF k8s.io/kubelet",
+		"node-1 CONTAINS Debian GNU/Linux 12 (bookworm)",
+		"node-1 CONTAINS registry.k8s.io/kube-apiserver",
+	}, relationships)
+
+	for _, pkg := range doc.Packages {
+		if pkg.PackageName == "nginx" {
+			var comments []string
+			for _, a := range pkg.Annotations {
+				comments = append(comments, a.AnnotationComment)
+			}
+			assert.Equal(t, []string{
+				"aquasecurity:trivy-kubernetes:Name: web",
+				"aquasecurity:trivy-kubernetes:Namespace: apps",
+				"aquasecurity:trivy-kubernetes:Type: helmRelease",
+			}, comments)
+		}
+	}
+}
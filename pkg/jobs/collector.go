@@ -231,7 +231,7 @@ func NewCollector(
 	jc := &jobCollector{
 		cluster:    cluster,
 		timeout:    0,
-		logsReader: NewLogsReader(cluster.GetK8sClientSet()),
+		logsReader: NewLogsReader(cluster.GetK8sClient()),
 	}
 	for _, opt := range opts {
 		opt(jc)
@@ -260,7 +260,7 @@ func (jb *jobCollector) ApplyAndCollect(ctx context.Context, nodeName string) (s
 	if err != nil {
 		if k8sapierror.IsNotFound(err) {
 			trivyNamespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: jb.namespace}}
-			_, err = jb.cluster.GetK8sClientSet().CoreV1().Namespaces().Create(ctx, trivyNamespace, metav1.CreateOptions{})
+			_, err = jb.cluster.GetK8sClient().CoreV1().Namespaces().Create(ctx, trivyNamespace, metav1.CreateOptions{})
 			if err != nil {
 				return "", err
 			}
@@ -310,13 +310,13 @@ func (jb *jobCollector) ApplyAndCollect(ctx context.Context, nodeName string) (s
 		return "", fmt.Errorf("running node-collector job: %w", err)
 	}
 
-	err = New(WithTimeout(jb.timeout)).Run(ctx, NewRunnableJob(jb.cluster.GetK8sClientSet(), job))
+	err = New(WithTimeout(jb.timeout)).Run(ctx, NewRunnableJob(jb.cluster.GetK8sClient(), job))
 	if err != nil {
 		return "", fmt.Errorf("running node-collector job: %w", err)
 	}
 	defer func() {
 		background := metav1.DeletePropagationBackground
-		_ = jb.cluster.GetK8sClientSet().BatchV1().Jobs(job.Namespace).Delete(ctx, job.Name, metav1.DeleteOptions{
+		_ = jb.cluster.GetK8sClient().BatchV1().Jobs(job.Namespace).Delete(ctx, job.Name, metav1.DeleteOptions{
 			PropagationPolicy: &background,
 		})
 	}()
@@ -336,7 +336,7 @@ func (jb *jobCollector) ApplyAndCollect(ctx context.Context, nodeName string) (s
 }
 
 func (jb jobCollector) loadNodeConfig(ctx context.Context, nodeName string) (string, error) {
-	data, err := jb.cluster.GetK8sClientSet().RESTClient().Get().AbsPath(fmt.Sprintf("/api/v1/nodes/%s/proxy/configz", nodeName)).DoRaw(ctx)
+	data, err := jb.cluster.GetK8sClient().CoreV1().RESTClient().Get().AbsPath(fmt.Sprintf("/api/v1/nodes/%s/proxy/configz", nodeName)).DoRaw(ctx)
 	if err != nil {
 		return "", err
 	}
@@ -530,7 +530,7 @@ func (jb *jobCollector) Apply(ctx context.Context, nodeName string) (*batchv1.Jo
 		return nil, fmt.Errorf("running node-collector job: %w", err)
 	}
 	// create job
-	job, err = jb.cluster.GetK8sClientSet().BatchV1().Jobs(job.Namespace).Create(ctx, job, metav1.CreateOptions{})
+	job, err = jb.cluster.GetK8sClient().BatchV1().Jobs(job.Namespace).Create(ctx, job, metav1.CreateOptions{})
 	if err != nil {
 		return nil, err
 	}
@@ -539,13 +539,13 @@ func (jb *jobCollector) Apply(ctx context.Context, nodeName string) (*batchv1.Jo
 
 func (jb *jobCollector) deleteTrivyNamespace(ctx context.Context) {
 	background := metav1.DeletePropagationBackground
-	_ = jb.cluster.GetK8sClientSet().CoreV1().Namespaces().Delete(ctx, jb.namespace, metav1.DeleteOptions{
+	_ = jb.cluster.GetK8sClient().CoreV1().Namespaces().Delete(ctx, jb.namespace, metav1.DeleteOptions{
 		PropagationPolicy: &background,
 	})
 }
 
 func (jb *jobCollector) getTrivyNamespace(ctx context.Context) (*corev1.Namespace, error) {
-	return jb.cluster.GetK8sClientSet().CoreV1().Namespaces().Get(ctx, jb.namespace, metav1.GetOptions{})
+	return jb.cluster.GetK8sClient().CoreV1().Namespaces().Get(ctx, jb.namespace, metav1.GetOptions{})
 }
 
 func (jb *jobCollector) Cleanup(ctx context.Context) {
@@ -0,0 +1,263 @@
+package k8s
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	containerimage "github.com/google/go-containerregistry/pkg/name"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/strings/slices"
+	"sigs.k8s.io/yaml"
+
+	"github.com/aquasecurity/trivy-kubernetes/pkg/bom"
+	"github.com/aquasecurity/trivy-kubernetes/utils"
+)
+
+//go:embed catalog.yaml
+var defaultComponentCatalog []byte
+
+// anyLabelValue matches any value of a rule label
+const anyLabelValue = "*"
+
+// ComponentCatalog detects the BOM components among the cluster pods, see
+// catalog.yaml for the embedded one
+type ComponentCatalog struct {
+	// Components are the detection rules, the first matching a pod wins
+	Components []ComponentRule `json:"components"`
+
+	// the orgs and types of the component names, set from the deprecated
+	// UpstreamOrgName and CoreComponentPropertyType by DefaultComponentCatalog
+	orgs  map[string]string
+	types map[string]string
+}
+
+// ComponentRule maps the pods it matches to a component
+type ComponentRule struct {
+	// Name is the component name, the value of the first of NameLabels the pod
+	// has when empty
+	Name       string   `json:"name,omitempty"`
+	NameLabels []string `json:"nameLabels,omitempty"`
+	// Org is the upstream organization prefixing the component name
+	Org string `json:"org,omitempty"`
+	// Type is the component type, unless the pod has an app.kubernetes.io/component label
+	Type string `json:"type,omitempty"`
+	// Namespaces restricts the rule to the pods of these namespaces
+	Namespaces []string `json:"namespaces,omitempty"`
+	// Labels matches the pods having all of them, "*" matching any value
+	Labels map[string]string `json:"labels,omitempty"`
+	// Images matches the pods running an image of these registry/repository
+	// patterns, "*" matching a path element
+	Images []string `json:"images,omitempty"`
+}
+
+// DefaultComponentCatalog returns the embedded component catalog, preceded by
+// the rules built from the current content of the deprecated UpstreamRepoName,
+// UpstreamOrgName and CoreComponentPropertyType
+func DefaultComponentCatalog() *ComponentCatalog {
+	catalog, err := LoadComponentCatalog(defaultComponentCatalog)
+	if err != nil {
+		panic(fmt.Sprintf("embedded component catalog: %v", err))
+	}
+	catalog.orgs = make(map[string]string)
+	for org, names := range UpstreamOrgName {
+		for _, name := range strings.Split(names, ",") {
+			catalog.orgs[strings.ToLower(strings.TrimSpace(name))] = org
+		}
+	}
+	catalog.types = make(map[string]string, len(CoreComponentPropertyType))
+	for name, componentType := range CoreComponentPropertyType {
+		catalog.types[strings.ToLower(name)] = componentType
+	}
+
+	// the component label values were mapped to their component name
+	values := make([]string, 0, len(UpstreamRepoName))
+	for value := range UpstreamRepoName {
+		values = append(values, value)
+	}
+	sort.Strings(values)
+	rules := make([]ComponentRule, 0, len(values)+len(catalog.Components))
+	for _, value := range values {
+		name := UpstreamRepoName[value]
+		rules = append(rules, ComponentRule{
+			Name:   name,
+			Org:    catalog.orgs[strings.ToLower(name)],
+			Type:   catalog.types[strings.ToLower(name)],
+			Labels: map[string]string{"component": value},
+		})
+	}
+	catalog.Components = append(rules, catalog.Components...)
+	return catalog
+}
+
+// LoadComponentCatalog parses a component catalog in the format of the embedded
+// one. It replaces the embedded catalog, whose rules can be appended to extend it
+func LoadComponentCatalog(data []byte) (*ComponentCatalog, error) {
+	catalog := &ComponentCatalog{}
+	if err := yaml.UnmarshalStrict(data, catalog); err != nil {
+		return nil, fmt.Errorf("parsing component catalog: %w", err)
+	}
+	if err := catalog.Validate(); err != nil {
+		return nil, err
+	}
+	return catalog, nil
+}
+
+// Validate checks every rule names its components and matches pods
+func (c *ComponentCatalog) Validate() error {
+	var errs []error
+	for i, r := range c.Components {
+		if r.Name == "" && len(r.NameLabels) == 0 {
+			errs = append(errs, fmt.Errorf("component rule %d: name or nameLabels is required", i))
+		}
+		if len(r.Labels) == 0 && len(r.Images) == 0 {
+			errs = append(errs, fmt.Errorf("component rule %d: labels or images is required", i))
+		}
+		for _, pattern := range r.Images {
+			if _, err := path.Match(pattern, ""); err != nil {
+				errs = append(errs, fmt.Errorf("component rule %d: image pattern %q: %w", i, pattern, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// componentMatch is a pod matched by a rule of the catalog
+type componentMatch struct {
+	rule *ComponentRule
+	name string
+	// versionHint is the label value matched by the rule, the version of the
+	// component is the one of the container whose image contains it
+	versionHint string
+}
+
+// componentType returns the type of a component name
+func (c *ComponentCatalog) componentType(name string) string {
+	return c.types[strings.ToLower(name)]
+}
+
+// match returns the first rule matching the pod, nil when none does
+func (c *ComponentCatalog) match(pod corev1.Pod) *componentMatch {
+	repositories := podRepositories(pod)
+	for i := range c.Components {
+		r := &c.Components[i]
+		if len(r.Namespaces) > 0 && !slices.Contains(r.Namespaces, pod.Namespace) {
+			continue
+		}
+		name := r.Name
+		if name == "" {
+			name = firstLabel(pod.Labels, r.NameLabels)
+			if name == "" {
+				continue
+			}
+		}
+		if r.matchesLabels(pod.Labels) || r.matchesImages(repositories) {
+			return &componentMatch{rule: r, name: name, versionHint: r.versionHint(pod.Labels)}
+		}
+	}
+	return nil
+}
+
+// org returns the upstream organization of a component name
+func (c *ComponentCatalog) org(name string) string {
+	if org, ok := c.orgs[strings.ToLower(name)]; ok {
+		return org
+	}
+	for _, r := range c.Components {
+		if r.Org != "" && strings.EqualFold(r.Name, name) {
+			return r.Org
+		}
+	}
+	return ""
+}
+
+func (r *ComponentRule) matchesLabels(labels map[string]string) bool {
+	if len(r.Labels) == 0 {
+		return false
+	}
+	for key, value := range r.Labels {
+		v, ok := labels[key]
+		if !ok || (value != anyLabelValue && v != value) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *ComponentRule) matchesImages(repositories []string) bool {
+	for _, repository := range repositories {
+		if r.matchesRepository(repository) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesRepository reports whether the registry/repository matches an image pattern
+func (r *ComponentRule) matchesRepository(repository string) bool {
+	for _, pattern := range r.Images {
+		if ok, _ := path.Match(pattern, repository); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// containerVersion returns the version of the first container of an image of
+// the rule
+func (r *ComponentRule) containerVersion(containers []bom.Container) string {
+	for _, c := range containers {
+		if r.matchesRepository(c.Registry + "/" + c.Repository) {
+			return c.Version
+		}
+	}
+	return ""
+}
+
+func (r *ComponentRule) versionHint(labels map[string]string) string {
+	if hint := firstLabel(labels, r.NameLabels); hint != "" {
+		return hint
+	}
+	keys := make([]string, 0, len(r.Labels))
+	for key := range r.Labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if labels[key] != "" {
+			return labels[key]
+		}
+	}
+	return ""
+}
+
+// podRepositories returns the registry/repository of the pod container images,
+// without the library/ namespace of the Docker Hub official images
+func podRepositories(pod corev1.Pod) []string {
+	repositories := make([]string, 0, len(pod.Spec.Containers))
+	for _, container := range pod.Spec.Containers {
+		image, _, _ := strings.Cut(container.Image, "@")
+		ref, err := utils.ParseReference(image)
+		if err != nil {
+			continue
+		}
+		registry, repository := ref.Context().RegistryStr(), ref.Context().RepositoryStr()
+		if registry == containerimage.DefaultRegistry {
+			repository = strings.TrimPrefix(repository, "library/")
+		}
+		repositories = append(repositories, registry+"/"+repository)
+	}
+	return repositories
+}
+
+func firstLabel(labels map[string]string, keys []string) string {
+	for _, key := range keys {
+		if v := labels[key]; v != "" {
+			return v
+		}
+	}
+	return ""
+}
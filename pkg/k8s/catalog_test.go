@@ -0,0 +1,262 @@
+package k8s
+
+import (
+	"context"
+	"maps"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+const catalogTestDigest = "sha256:0f5ac3a0c3d5ff2b1a0fb5bf9d8b1bb1c5e6f6b9c3e8ad5c8a2e1f7b4d3c2a19"
+
+func catalogTestPod(namespace, name string, labels map[string]string, image string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: labels},
+		Spec: corev1.PodSpec{
+			NodeName:   "node-1",
+			Containers: []corev1.Container{{Name: "main", Image: image}},
+		},
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{{Image: image, ImageID: "docker-pullable://" + image + "@" + catalogTestDigest}},
+		},
+	}
+}
+
+func TestDefaultComponentCatalog(t *testing.T) {
+	tests := []struct {
+		name        string
+		pod         *corev1.Pod
+		wantName    string
+		wantVersion string
+		wantType    string
+	}{
+		{
+			name:        "cilium agent by label",
+			pod:         catalogTestPod("kube-system", "cilium-x2v9k", map[string]string{"k8s-app": "cilium"}, "quay.io/cilium/cilium:v1.15.6"),
+			wantName:    "github.com/cilium/cilium",
+			wantVersion: "1.15.6",
+			wantType:    "networkPlugin",
+		},
+		{
+			name:        "calico node by image in another namespace",
+			pod:         catalogTestPod("calico-system", "calico-node-4wq8d", nil, "docker.io/calico/node:v3.28.0"),
+			wantName:    "github.com/projectcalico/calico",
+			wantVersion: "3.28.0",
+			wantType:    "networkPlugin",
+		},
+		{
+			name:        "istiod",
+			pod:         catalogTestPod("istio-system", "istiod-7d4b9c7f5d-xk2lp", map[string]string{"app": "istiod", "istio.io/rev": "default"}, "docker.io/istio/pilot:1.22.1"),
+			wantName:    "istio.io/istio",
+			wantVersion: "1.22.1",
+			wantType:    "serviceMesh",
+		},
+		{
+			name: "cert-manager keeps its component label type",
+			pod: catalogTestPod("cert-manager", "cert-manager-webhook-6d5cb854fc-9xj7h", map[string]string{
+				"app.kubernetes.io/instance":  "cert-manager",
+				"app.kubernetes.io/name":      "webhook",
+				"app.kubernetes.io/component": "webhook",
+				"app.kubernetes.io/version":   "v1.15.1",
+			}, "quay.io/jetstack/cert-manager-webhook:v1.15.1"),
+			wantName:    "github.com/cert-manager/cert-manager",
+			wantVersion: "v1.15.1",
+			wantType:    "webhook",
+		},
+		{
+			name:        "ebs csi driver from a mirrored registry",
+			pod:         catalogTestPod("kube-system", "ebs-csi-node-8hz4c", map[string]string{"app": "ebs-csi-node"}, "602401143452.dkr.ecr.us-west-2.amazonaws.com/eks/aws-ebs-csi-driver:v1.32.0"),
+			wantName:    "github.com/kubernetes-sigs/aws-ebs-csi-driver",
+			wantVersion: "1.32.0",
+			wantType:    "csiDriver",
+		},
+		{
+			name:        "traefik official image",
+			pod:         catalogTestPod("traefik", "traefik-5f8b7c6d9-q7m2n", nil, "traefik:v3.0.4"),
+			wantName:    "github.com/traefik/traefik",
+			wantVersion: "3.0.4",
+			wantType:    "ingressController",
+		},
+		{
+			name:        "coredns",
+			pod:         catalogTestPod("kube-system", "coredns-76f75df574-8xk2p", map[string]string{"k8s-app": "kube-dns"}, "registry.k8s.io/coredns/coredns:v1.11.1"),
+			wantName:    "github.com/coredns/coredns",
+			wantVersion: "1.11.1",
+			wantType:    "dns",
+		},
+		{
+			name:        "kube-proxy daemonset",
+			pod:         catalogTestPod("kube-system", "kube-proxy-6kdzm", map[string]string{"k8s-app": "kube-proxy"}, "registry.k8s.io/kube-proxy:v1.30.2"),
+			wantName:    "k8s.io/kube-proxy",
+			wantVersion: "1.30.2",
+			wantType:    "node",
+		},
+		{
+			name:     "openshift apiserver",
+			pod:      catalogTestPod("openshift-kube-apiserver", "kube-apiserver-master-0", map[string]string{"apiserver": "true", "app": "openshift-kube-apiserver"}, "quay.io/openshift-release-dev/ocp-v4.0-art-dev:latest"),
+			wantName: "k8s.io/apiserver",
+			wantType: "controlPlane",
+		},
+		{
+			name:        "unknown kube-system addon falls back to its label",
+			pod:         catalogTestPod("kube-system", "metrics-server-6d94bc8694-hxq4j", map[string]string{"k8s-app": "metrics-server"}, "registry.k8s.io/metrics-server/metrics-server:v0.7.1"),
+			wantName:    "metrics-server",
+			wantVersion: "0.7.1",
+		},
+	}
+	catalog := DefaultComponentCatalog()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := PodComponent(*test.pod, catalog)
+			require.NoError(t, err)
+			assert.Equal(t, test.wantName, got.Name)
+			assert.Equal(t, test.wantVersion, got.Version)
+			assert.Equal(t, test.wantType, got.Properties["Type"])
+		})
+	}
+
+	web := catalogTestPod("apps", "web-7c9d8b5f4-2kq7x", map[string]string{"app": "web"}, "nginx:1.27")
+	_, err := PodComponent(*web, catalog)
+	assert.ErrorContains(t, err, "no component rule matches pod apps/web-7c9d8b5f4-2kq7x")
+
+	got, err := PodInfo(*web, "app")
+	require.NoError(t, err, "the deprecated PodInfo falls back to the label selector")
+	assert.Equal(t, "web", got.Name)
+}
+
+func TestDefaultComponentCatalogDeprecatedMaps(t *testing.T) {
+	upstreamOrgName, upstreamRepoName, coreComponentPropertyType := maps.Clone(UpstreamOrgName), maps.Clone(UpstreamRepoName), maps.Clone(CoreComponentPropertyType)
+	defer func() {
+		UpstreamOrgName, UpstreamRepoName, CoreComponentPropertyType = upstreamOrgName, upstreamRepoName, coreComponentPropertyType
+	}()
+	UpstreamOrgName["github.com/acme"] = "widget,gadget"
+	UpstreamRepoName["acme-widget"] = "widget"
+	CoreComponentPropertyType["widget"] = "addon"
+
+	catalog := DefaultComponentCatalog()
+	widget, err := PodComponent(*catalogTestPod("acme", "widget-0", map[string]string{"component": "acme-widget"}, "ghcr.io/acme/widget:v2.1.0"), catalog)
+	require.NoError(t, err)
+	assert.Equal(t, "github.com/acme/widget", widget.Name)
+	assert.Equal(t, "addon", widget.Properties["Type"])
+
+	gadget, err := PodComponent(*catalogTestPod("acme", "gadget-0", map[string]string{"component": "gadget"}, "ghcr.io/acme/gadget:v1.0.0"), catalog)
+	require.NoError(t, err)
+	assert.Equal(t, "github.com/acme/gadget", gadget.Name, "the org of a component named by the fallback rules")
+}
+
+func TestLoadComponentCatalog(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{
+			name: "valid",
+			data: `
+components:
+  - name: payments
+    org: example.com
+    type: application
+    namespaces: [payments]
+    images: ["*/payments/api"]
+`,
+		},
+		{
+			name:    "unknown field",
+			data:    "components:\n  - name: a\n    label: {a: b}\n",
+			wantErr: "parsing component catalog",
+		},
+		{
+			name:    "no name",
+			data:    "components:\n  - labels: {a: b}\n",
+			wantErr: "component rule 0: name or nameLabels is required",
+		},
+		{
+			name:    "no selector",
+			data:    "components:\n  - name: a\n",
+			wantErr: "component rule 0: labels or images is required",
+		},
+		{
+			name:    "bad pattern",
+			data:    "components:\n  - name: a\n    images: [\"[\"]\n",
+			wantErr: `component rule 0: image pattern "["`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			catalog, err := LoadComponentCatalog([]byte(test.data))
+			if test.wantErr != "" {
+				assert.ErrorContains(t, err, test.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, catalog.Components, 1)
+		})
+	}
+}
+
+func TestCreateBomComponentsWithCatalog(t *testing.T) {
+	c, err := NewCluster(
+		fake.NewClientset(
+			catalogTestPod("payments", "api-5b6f9d7c8-7nq2w", nil, "registry.example.com/payments/api:2.4.0"),
+			catalogTestPod("kube-system", "cilium-x2v9k", map[string]string{"k8s-app": "cilium"}, "quay.io/cilium/cilium:v1.15.6"),
+		),
+		fakedynamic.NewSimpleDynamicClient(runtime.NewScheme()),
+		meta.NewDefaultRESTMapper(nil),
+	)
+	require.NoError(t, err)
+
+	components, err := c.CreateBomComponents(context.Background(), "")
+	require.NoError(t, err)
+	require.Len(t, components, 1, "the default catalog doesn't know the payments api")
+	assert.Equal(t, "github.com/cilium/cilium", components[0].Name)
+
+	catalog := DefaultComponentCatalog()
+	catalog.Components = append([]ComponentRule{
+		{Name: "api", Org: "example.com/payments", Type: "application", Images: []string{"*/payments/api"}},
+	}, catalog.Components...)
+
+	components, err = c.CreateBomComponents(context.Background(), "payments", WithComponentCatalog(catalog))
+	require.NoError(t, err)
+	require.Len(t, components, 1)
+	assert.Equal(t, "example.com/payments/api", components[0].Name)
+	assert.Equal(t, "2.4.0", components[0].Version)
+	assert.Equal(t, "node-1", components[0].NodeName)
+}
+
+func TestCollectComponentsPages(t *testing.T) {
+	pods := []corev1.Pod{
+		*catalogTestPod("kube-system", "cilium-x2v9k", map[string]string{"k8s-app": "cilium"}, "quay.io/cilium/cilium:v1.15.6"),
+		*catalogTestPod("kube-system", "coredns-76f75df574-8xk2p", map[string]string{"k8s-app": "kube-dns"}, "registry.k8s.io/coredns/coredns:v1.11.1"),
+	}
+	clientset := fake.NewClientset()
+	var limits []int64
+	clientset.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		opts := action.(k8stesting.ListActionImpl).ListOptions
+		limits = append(limits, opts.Limit)
+		list := &corev1.PodList{Items: pods[:1]}
+		list.Continue = "page-2"
+		if opts.Continue == "page-2" {
+			list = &corev1.PodList{Items: pods[1:]}
+		}
+		return true, list, nil
+	})
+	c, err := NewCluster(clientset, fakedynamic.NewSimpleDynamicClient(runtime.NewScheme()), meta.NewDefaultRESTMapper(nil))
+	require.NoError(t, err)
+
+	components, err := c.(*cluster).collectComponents(context.Background(), "", DefaultComponentCatalog())
+	require.NoError(t, err)
+	assert.Equal(t, []int64{componentPodsPageSize, componentPodsPageSize}, limits)
+	require.Len(t, components, 2)
+	assert.Equal(t, "github.com/cilium/cilium", components[0].Name)
+	assert.Equal(t, "github.com/coredns/coredns", components[1].Name)
+}
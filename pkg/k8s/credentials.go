@@ -0,0 +1,129 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	k8sapierror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+)
+
+// CredentialCache holds the service accounts and image pull secrets read by
+// AuthByResource for the duration of a scan. They are listed once per namespace,
+// or read one by one and kept when they can't be listed
+type CredentialCache struct {
+	mu         sync.Mutex
+	namespaces map[string]*namespaceCredentials
+}
+
+// NewCredentialCache returns an empty cache, to share between the AuthByResource
+// calls of a scan
+func NewCredentialCache() *CredentialCache {
+	return &CredentialCache{namespaces: make(map[string]*namespaceCredentials)}
+}
+
+// namespaceCredentials are the credentials of a namespace, its lock is held while
+// they are read
+type namespaceCredentials struct {
+	mu                    sync.Mutex
+	loaded                bool
+	serviceAccounts       map[string]*corev1.ServiceAccount
+	serviceAccountsListed bool
+	secrets               map[string]*corev1.Secret
+	secretsListed         bool
+}
+
+// namespace returns the credentials of the namespace, the cache lock is only held
+// to find them
+func (c *CredentialCache) namespace(ns string) *namespaceCredentials {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	creds, ok := c.namespaces[ns]
+	if !ok {
+		creds = &namespaceCredentials{}
+		c.namespaces[ns] = creds
+	}
+	return creds
+}
+
+// loadNamespaceCredentials lists the service accounts and image pull secrets of
+// the namespace the first time it is seen, the caller holds the namespace lock
+func (r *cluster) loadNamespaceCredentials(ctx context.Context, ns string, creds *namespaceCredentials) error {
+	if creds.loaded {
+		return nil
+	}
+	creds.serviceAccounts = make(map[string]*corev1.ServiceAccount)
+	creds.secrets = make(map[string]*corev1.Secret)
+
+	serviceAccounts, err := r.clientset.CoreV1().ServiceAccounts(ns).List(ctx, metav1.ListOptions{})
+	switch {
+	case err == nil:
+		for i := range serviceAccounts.Items {
+			creds.serviceAccounts[serviceAccounts.Items[i].Name] = &serviceAccounts.Items[i]
+		}
+		creds.serviceAccountsListed = true
+	case !k8sapierror.IsForbidden(err):
+		return fmt.Errorf("listing service accounts: %s: %w", ns, err)
+	}
+
+	creds.secretsListed = true
+	for _, secretType := range []corev1.SecretType{corev1.SecretTypeDockerConfigJson, corev1.SecretTypeDockercfg} {
+		secrets, err := r.clientset.CoreV1().Secrets(ns).List(ctx, metav1.ListOptions{
+			FieldSelector: fields.OneTermEqualSelector("type", string(secretType)).String(),
+		})
+		if k8sapierror.IsForbidden(err) {
+			creds.secretsListed = false
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("listing image pull secrets: %s: %w", ns, err)
+		}
+		for i := range secrets.Items {
+			if secrets.Items[i].Type == secretType {
+				creds.secrets[secrets.Items[i].Name] = &secrets.Items[i]
+			}
+		}
+	}
+	if !creds.secretsListed {
+		clear(creds.secrets)
+	}
+	creds.loaded = true
+	return nil
+}
+
+// serviceAccount returns the cached service account, nil when it doesn't exist
+// or can't be read
+func (r *cluster) serviceAccount(ctx context.Context, creds *namespaceCredentials, ns, name string) (*corev1.ServiceAccount, error) {
+	if sa, ok := creds.serviceAccounts[name]; ok || creds.serviceAccountsListed {
+		return sa, nil
+	}
+	sa, err := r.clientset.CoreV1().ServiceAccounts(ns).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if !k8sapierror.IsNotFound(err) && !k8sapierror.IsForbidden(err) {
+			return nil, fmt.Errorf("getting service account by name: %s/%s: %w", ns, name, err)
+		}
+		sa = nil
+	}
+	creds.serviceAccounts[name] = sa
+	return sa, nil
+}
+
+// secret returns the cached image pull secret, nil when it doesn't exist or
+// can't be read
+func (r *cluster) secret(ctx context.Context, creds *namespaceCredentials, ns, name string) (*corev1.Secret, error) {
+	if secret, ok := creds.secrets[name]; ok || creds.secretsListed {
+		return secret, nil
+	}
+	secret, err := r.clientset.CoreV1().Secrets(ns).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if !k8sapierror.IsNotFound(err) && !k8sapierror.IsForbidden(err) {
+			return nil, fmt.Errorf("getting secret by name: %s/%s: %w", ns, name, err)
+		}
+		secret = nil
+	}
+	creds.secrets[name] = secret
+	return secret, nil
+}
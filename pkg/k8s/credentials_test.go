@@ -0,0 +1,173 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	k8sapierror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/aquasecurity/trivy-kubernetes/pkg/k8s/docker"
+)
+
+func TestListImagePullSecretsByPodSpecCache(t *testing.T) {
+	objects := []runtime.Object{
+		&corev1.ServiceAccount{
+			ObjectMeta:       metav1.ObjectMeta{Name: "default", Namespace: "app"},
+			ImagePullSecrets: []corev1.LocalObjectReference{{Name: "ghcr"}},
+		},
+		&corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "builder", Namespace: "app"}},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "ghcr", Namespace: "app"},
+			Type:       corev1.SecretTypeDockerConfigJson,
+			Data: map[string][]byte{
+				corev1.DockerConfigJsonKey: []byte(`{"auths":{"ghcr.io":{"username":"user","password":"pass"}}}`),
+			},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "quay", Namespace: "app"},
+			Type:       corev1.SecretTypeDockercfg,
+			Data: map[string][]byte{
+				corev1.DockerConfigKey: []byte(`{"quay.io":{"username":"robot","password":"token"}}`),
+			},
+		},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "tls", Namespace: "app"}, Type: corev1.SecretTypeTLS},
+	}
+	specs := []*corev1.PodSpec{
+		{},
+		{ServiceAccountName: "builder", ImagePullSecrets: []corev1.LocalObjectReference{{Name: "quay"}, {Name: "tls"}}},
+		{ServiceAccountName: "missing", ImagePullSecrets: []corev1.LocalObjectReference{{Name: "missing"}}},
+		{},
+	}
+	want := []map[string]docker.Auth{
+		{"ghcr.io": {Username: "user", Password: "pass"}},
+		{"quay.io": {Username: "robot", Password: "token"}},
+		{},
+		{"ghcr.io": {Username: "user", Password: "pass"}},
+	}
+
+	tests := []struct {
+		name      string
+		forbidden []string
+		wantCalls map[string]int
+	}{
+		{
+			name:      "namespace listed once",
+			wantCalls: map[string]int{"list serviceaccounts": 1, "list secrets": 2},
+		},
+		{
+			name:      "listing forbidden",
+			forbidden: []string{"serviceaccounts", "secrets"},
+			wantCalls: map[string]int{
+				"list serviceaccounts": 1,
+				"list secrets":         1,
+				"get serviceaccounts":  3,
+				"get secrets":          4,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clientset := fake.NewClientset(objects...)
+			clientset.Discovery().(*fakediscovery.FakeDiscovery).FakedServerVersion = &version.Info{GitVersion: "v1.30.0"}
+			calls := make(map[string]int)
+			clientset.PrependReactor("*", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				calls[action.GetVerb()+" "+action.GetResource().Resource]++
+				return false, nil, nil
+			})
+			for _, resource := range test.forbidden {
+				clientset.PrependReactor("list", resource, func(action k8stesting.Action) (bool, runtime.Object, error) {
+					calls[action.GetVerb()+" "+action.GetResource().Resource]++
+					return true, nil, k8sapierror.NewForbidden(schema.GroupResource{Resource: resource}, "", errors.New("forbidden"))
+				})
+			}
+			c, err := NewCluster(clientset, nil, nil)
+			require.NoError(t, err)
+			clear(calls)
+
+			cache := NewCredentialCache()
+			for i, spec := range specs {
+				auths, err := c.(*cluster).ListImagePullSecretsByPodSpec(context.Background(), spec, "app", cache)
+				require.NoError(t, err)
+				assert.Equal(t, want[i], auths)
+			}
+			assert.Equal(t, test.wantCalls, calls)
+
+			_, err = c.(*cluster).ListImagePullSecretsByPodSpec(context.Background(), specs[0], "app", NewCredentialCache())
+			require.NoError(t, err)
+			assert.Equal(t, test.wantCalls["list serviceaccounts"]*2, calls["list serviceaccounts"])
+		})
+	}
+}
+
+func TestListImagePullSecretsByPodSpecWithoutCache(t *testing.T) {
+	clientset := fake.NewClientset(
+		&corev1.ServiceAccount{
+			ObjectMeta:       metav1.ObjectMeta{Name: "default", Namespace: "app"},
+			ImagePullSecrets: []corev1.LocalObjectReference{{Name: "ghcr"}},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "ghcr", Namespace: "app"},
+			Type:       corev1.SecretTypeDockerConfigJson,
+			Data: map[string][]byte{
+				corev1.DockerConfigJsonKey: []byte(`{"auths":{"ghcr.io":{"username":"user","password":"pass"}}}`),
+			},
+		},
+	)
+	clientset.Discovery().(*fakediscovery.FakeDiscovery).FakedServerVersion = &version.Info{GitVersion: "v1.30.0"}
+	calls := make(map[string]int)
+	clientset.PrependReactor("*", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		calls[action.GetVerb()+" "+action.GetResource().Resource]++
+		return false, nil, nil
+	})
+	c, err := NewCluster(clientset, nil, nil)
+	require.NoError(t, err)
+	clear(calls)
+
+	for range 2 {
+		auths, err := c.(*cluster).ListImagePullSecretsByPodSpec(context.Background(), &corev1.PodSpec{}, "app", nil)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]docker.Auth{"ghcr.io": {Username: "user", Password: "pass"}}, auths)
+	}
+	assert.Equal(t, map[string]int{"get serviceaccounts": 2, "get secrets": 2}, calls, "only the referenced credentials are read")
+}
+
+func TestCredentialCacheLocksPerNamespace(t *testing.T) {
+	clientset := fake.NewClientset()
+	clientset.Discovery().(*fakediscovery.FakeDiscovery).FakedServerVersion = &version.Info{GitVersion: "v1.30.0"}
+	c, err := NewCluster(clientset, nil, nil)
+	require.NoError(t, err)
+
+	cache := NewCredentialCache()
+	// the credentials of the slow namespace are being read
+	slow := cache.namespace("slow")
+	slow.mu.Lock()
+	fast := make(chan error)
+	go func() {
+		_, err := c.(*cluster).ListImagePullSecretsByPodSpec(context.Background(), &corev1.PodSpec{}, "fast", cache)
+		fast <- err
+	}()
+	select {
+	case err := <-fast:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("reading a namespace waits for the credentials of another")
+	}
+	slow.mu.Unlock()
+
+	_, err = c.(*cluster).ListImagePullSecretsByPodSpec(context.Background(), &corev1.PodSpec{}, "slow", cache)
+	require.NoError(t, err)
+	assert.True(t, slow.loaded)
+}
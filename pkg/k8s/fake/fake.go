@@ -0,0 +1,357 @@
+// Package fake provides an in-memory k8s.Cluster backed by client-go fakes, for
+// testing code built on this library without a real cluster
+package fake
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8sapierror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/apimachinery/pkg/version"
+	"k8s.io/apimachinery/pkg/watch"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+	fakekubernetes "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/metadata"
+	fakemetadata "k8s.io/client-go/metadata/fake"
+	"k8s.io/client-go/rest"
+	fakerest "k8s.io/client-go/rest/fake"
+	"k8s.io/client-go/restmapper"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/aquasecurity/trivy-kubernetes/pkg/k8s"
+)
+
+const (
+	defaultVersion       = "v1.31.0"
+	defaultKubeletConfig = `{"kubeletconfig":{}}`
+	jobControllerUID     = "batch.kubernetes.io/controller-uid"
+)
+
+var (
+	jobsResource = schema.GroupVersionResource{Group: "batch", Version: "v1", Resource: "jobs"}
+	podsResource = schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+)
+
+// Cluster is a k8s.Cluster serving the seeded objects. Objects are served by three
+// independent fakes: the typed Clientset, the DynamicClient and the MetadataClient,
+// objects created through one of them are not visible to the others.
+//
+// Jobs created through the Clientset complete immediately with a terminated pod,
+// whose logs are the client-go fake logs, and the kubelet configuration of every
+// node is served through the core REST client
+type Cluster struct {
+	k8s.Cluster
+	Clientset      *fakekubernetes.Clientset
+	DynamicClient  *fakedynamic.FakeDynamicClient
+	MetadataClient *fakemetadata.FakeMetadataClient
+	platform       *k8s.Platform
+}
+
+type options struct {
+	objects       []runtime.Object
+	yamlDocs      []string
+	version       string
+	platform      *k8s.Platform
+	forbidden     []string
+	kubeletConfig string
+}
+
+type Option func(*options)
+
+// WithObjects seeds the cluster with typed or unstructured objects
+func WithObjects(objects ...runtime.Object) Option {
+	return func(o *options) {
+		o.objects = append(o.objects, objects...)
+	}
+}
+
+// WithYAML seeds the cluster with the objects of YAML documents, a document can
+// hold several objects separated by ---
+func WithYAML(docs ...string) Option {
+	return func(o *options) {
+		o.yamlDocs = append(o.yamlDocs, docs...)
+	}
+}
+
+// WithVersion sets the server git version, v1.31.0 by default
+func WithVersion(gitVersion string) Option {
+	return func(o *options) {
+		o.version = gitVersion
+	}
+}
+
+// WithPlatform sets the platform returned by the cluster instead of detecting it
+// from the seeded objects
+func WithPlatform(platform k8s.Platform) Option {
+	return func(o *options) {
+		o.platform = &platform
+	}
+}
+
+// WithForbidden makes every request on the resources, such as secrets or nodes,
+// fail with a Forbidden error
+func WithForbidden(resources ...string) Option {
+	return func(o *options) {
+		o.forbidden = append(o.forbidden, resources...)
+	}
+}
+
+// WithKubeletConfig sets the kubelet configuration served for the nodes
+func WithKubeletConfig(kubeletConfig string) Option {
+	return func(o *options) {
+		o.kubeletConfig = kubeletConfig
+	}
+}
+
+// NewCluster returns a fake cluster seeded with the given objects
+func NewCluster(opts ...Option) (*Cluster, error) {
+	o := &options{
+		version:       defaultVersion,
+		kubeletConfig: defaultKubeletConfig,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	objects := o.objects
+	for _, doc := range o.yamlDocs {
+		decoded, err := decodeYAML(doc)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, decoded...)
+	}
+
+	typed := make([]runtime.Object, 0, len(objects))
+	dynamic := make([]runtime.Object, 0, len(objects))
+	partial := make([]runtime.Object, 0, len(objects))
+	for _, obj := range objects {
+		u, err := toUnstructured(obj)
+		if err != nil {
+			return nil, err
+		}
+		dynamic = append(dynamic, u)
+		partial = append(partial, toPartialObjectMetadata(u))
+		if t, err := scheme.Scheme.New(u.GroupVersionKind()); err == nil {
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, t); err != nil {
+				return nil, fmt.Errorf("converting %s %s: %w", u.GetKind(), u.GetName(), err)
+			}
+			typed = append(typed, t)
+		}
+	}
+
+	clientset := fakekubernetes.NewClientset(typed...)
+	dynamicClient := fakedynamic.NewSimpleDynamicClient(scheme.Scheme, dynamic...)
+	metadataScheme := runtime.NewScheme()
+	if err := metav1.AddMetaToScheme(metadataScheme); err != nil {
+		return nil, err
+	}
+	metadataClient := fakemetadata.NewSimpleMetadataClient(metadataScheme, partial...)
+
+	groupResources := apiGroupResources(dynamic)
+	discovery := clientset.Discovery().(*fakediscovery.FakeDiscovery)
+	discovery.FakedServerVersion = &version.Info{GitVersion: o.version}
+	discovery.Resources = apiResourceLists(groupResources)
+
+	clientset.PrependReactor("create", "jobs", completeJob(clientset.Tracker()))
+	clientset.PrependWatchReactor("jobs", watchCompletedJobs(clientset.Tracker()))
+	for _, resource := range o.forbidden {
+		clientset.PrependReactor("*", resource, forbidden(resource))
+		dynamicClient.PrependReactor("*", resource, forbidden(resource))
+		metadataClient.PrependReactor("*", resource, forbidden(resource))
+	}
+
+	cluster, err := k8s.NewCluster(
+		&kubernetesClient{Clientset: clientset, restClient: kubeletConfigClient(o.kubeletConfig)},
+		dynamicClient,
+		restmapper.NewDiscoveryRESTMapper(groupResources),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &Cluster{
+		Cluster:        cluster,
+		Clientset:      clientset,
+		DynamicClient:  dynamicClient,
+		MetadataClient: metadataClient,
+		platform:       o.platform,
+	}, nil
+}
+
+// GetMetadataClient returns the MetadataClient
+func (c *Cluster) GetMetadataClient() metadata.Interface {
+	return c.MetadataClient
+}
+
+// Platform returns the configured platform, or the one detected from the seeded objects
+func (c *Cluster) Platform() k8s.Platform {
+	if c.platform != nil {
+		return *c.platform
+	}
+	return c.Cluster.Platform()
+}
+
+func decodeYAML(doc string) ([]runtime.Object, error) {
+	objects := make([]runtime.Object, 0)
+	decoder := yaml.NewYAMLOrJSONDecoder(strings.NewReader(doc), 4096)
+	for {
+		u := &unstructured.Unstructured{}
+		if err := decoder.Decode(&u.Object); err != nil {
+			if errors.Is(err, io.EOF) {
+				return objects, nil
+			}
+			return nil, fmt.Errorf("decoding YAML: %w", err)
+		}
+		if len(u.Object) == 0 {
+			continue
+		}
+		if u.GetKind() == "" {
+			return nil, fmt.Errorf("decoding YAML: object %q has no kind", u.GetName())
+		}
+		objects = append(objects, u)
+	}
+}
+
+func toUnstructured(obj runtime.Object) (*unstructured.Unstructured, error) {
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		return u, nil
+	}
+	gvks, _, err := scheme.Scheme.ObjectKinds(obj)
+	if err != nil {
+		return nil, err
+	}
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	u := &unstructured.Unstructured{Object: content}
+	u.SetGroupVersionKind(gvks[0])
+	return u, nil
+}
+
+func toPartialObjectMetadata(u *unstructured.Unstructured) *metav1.PartialObjectMetadata {
+	return &metav1.PartialObjectMetadata{
+		TypeMeta: metav1.TypeMeta{APIVersion: u.GetAPIVersion(), Kind: u.GetKind()},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            u.GetName(),
+			Namespace:       u.GetNamespace(),
+			UID:             u.GetUID(),
+			Labels:          u.GetLabels(),
+			Annotations:     u.GetAnnotations(),
+			OwnerReferences: u.GetOwnerReferences(),
+		},
+	}
+}
+
+func forbidden(resource string) k8stesting.ReactionFunc {
+	return func(action k8stesting.Action) (bool, runtime.Object, error) {
+		gr := schema.GroupResource{Group: action.GetResource().Group, Resource: resource}
+		return true, nil, k8sapierror.NewForbidden(gr, "", errors.New("forbidden by the fake cluster"))
+	}
+}
+
+// completeJob stores created jobs with the selector set by the api server and a
+// terminated pod, as the job controller would
+func completeJob(tracker k8stesting.ObjectTracker) k8stesting.ReactionFunc {
+	return func(action k8stesting.Action) (bool, runtime.Object, error) {
+		job := action.(k8stesting.CreateAction).GetObject().(*batchv1.Job).DeepCopy()
+		if job.Name == "" {
+			job.Name = job.GenerateName + "fake"
+		}
+		job.UID = types.UID(job.Namespace + "-" + job.Name)
+		job.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{jobControllerUID: string(job.UID)}}
+		if err := tracker.Create(jobsResource, job, job.Namespace); err != nil {
+			return true, nil, err
+		}
+
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      job.Name + "-pod",
+				Namespace: job.Namespace,
+				Labels:    map[string]string{jobControllerUID: string(job.UID)},
+			},
+			Spec: job.Spec.Template.Spec,
+		}
+		for _, c := range pod.Spec.Containers {
+			pod.Status.ContainerStatuses = append(pod.Status.ContainerStatuses, corev1.ContainerStatus{
+				Name:  c.Name,
+				State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "Completed"}},
+			})
+		}
+		if err := tracker.Create(podsResource, pod, pod.Namespace); err != nil && !k8sapierror.IsAlreadyExists(err) {
+			return true, nil, err
+		}
+		return true, job, nil
+	}
+}
+
+// watchCompletedJobs reports the existing jobs of the namespace as completed
+func watchCompletedJobs(tracker k8stesting.ObjectTracker) k8stesting.WatchReactionFunc {
+	return func(action k8stesting.Action) (bool, watch.Interface, error) {
+		obj, err := tracker.List(jobsResource, batchv1.SchemeGroupVersion.WithKind("Job"), action.GetNamespace())
+		if err != nil {
+			return true, nil, err
+		}
+		w := watch.NewRaceFreeFake()
+		for _, job := range obj.(*batchv1.JobList).Items {
+			job.Status.Succeeded = 1
+			job.Status.Conditions = append(job.Status.Conditions, batchv1.JobCondition{
+				Type:   batchv1.JobComplete,
+				Status: corev1.ConditionTrue,
+			})
+			w.Modify(&job)
+		}
+		return true, w, nil
+	}
+}
+
+// kubernetesClient serves the node proxy requests of the core REST client,
+// which the client-go fake leaves unset
+type kubernetesClient struct {
+	*fakekubernetes.Clientset
+	restClient rest.Interface
+}
+
+func (c *kubernetesClient) CoreV1() corev1client.CoreV1Interface {
+	return &coreV1Client{CoreV1Interface: c.Clientset.CoreV1(), restClient: c.restClient}
+}
+
+type coreV1Client struct {
+	corev1client.CoreV1Interface
+	restClient rest.Interface
+}
+
+func (c *coreV1Client) RESTClient() rest.Interface {
+	return c.restClient
+}
+
+func kubeletConfigClient(kubeletConfig string) rest.Interface {
+	return &fakerest.RESTClient{
+		NegotiatedSerializer: scheme.Codecs.WithoutConversion(),
+		Client: fakerest.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			if !strings.HasSuffix(req.URL.Path, "/proxy/configz") {
+				return &http.Response{StatusCode: http.StatusNotFound, Body: io.NopCloser(&bytes.Buffer{})}, nil
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Content-Type": []string{"application/json"}},
+				Body:       io.NopCloser(strings.NewReader(kubeletConfig)),
+			}, nil
+		}),
+	}
+}
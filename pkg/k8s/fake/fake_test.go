@@ -0,0 +1,129 @@
+package fake_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	k8sapierror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/aquasecurity/trivy-kubernetes/pkg/artifacts"
+	"github.com/aquasecurity/trivy-kubernetes/pkg/jobs"
+	"github.com/aquasecurity/trivy-kubernetes/pkg/k8s"
+	"github.com/aquasecurity/trivy-kubernetes/pkg/k8s/fake"
+	"github.com/aquasecurity/trivy-kubernetes/pkg/trivyk8s"
+)
+
+const objects = `
+apiVersion: v1
+kind: Node
+metadata:
+  name: node-1
+spec:
+  providerID: kind://docker/kind/node-1
+status:
+  nodeInfo:
+    kubeletVersion: v1.30.0
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: default
+  namespace: app
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: api
+  namespace: app
+spec:
+  template:
+    spec:
+      containers:
+        - name: api
+          image: ghcr.io/acme/api:1.0
+---
+apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: api
+  namespace: argocd
+`
+
+func TestNewCluster(t *testing.T) {
+	cluster, err := fake.NewCluster(fake.WithYAML(objects), fake.WithVersion("v1.30.0"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "1.30.0", cluster.GetClusterVersion())
+	assert.Equal(t, "kind", cluster.Platform().Name)
+
+	gvr, err := cluster.GetGVR("applications")
+	require.NoError(t, err)
+	assert.Equal(t, "argoproj.io", gvr.Group)
+
+	serviceAccounts, err := cluster.GetMetadataClient().Resource(schema.GroupVersionResource{Version: "v1", Resource: "serviceaccounts"}).
+		Namespace("app").List(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, serviceAccounts.Items, 1)
+	assert.Equal(t, "default", serviceAccounts.Items[0].Name)
+
+	artifactList, err := trivyk8s.New(cluster).Namespace("app").ListArtifacts(context.Background())
+	require.NoError(t, err)
+	kinds := make(map[string]string)
+	for _, a := range artifactList {
+		kinds[a.Kind] = a.Name
+	}
+	assert.Equal(t, map[string]string{"Deployment": "api", "ServiceAccount": "default"}, kinds)
+
+	bomArtifacts, err := trivyk8s.New(cluster).ListClusterBomInfo(context.Background())
+	require.NoError(t, err)
+	var nodes []*artifacts.Artifact
+	for _, a := range bomArtifacts {
+		if a.Kind == "NodeComponents" {
+			nodes = append(nodes, a)
+		}
+	}
+	require.Len(t, nodes, 1)
+	assert.Equal(t, "node-1", nodes[0].Name)
+}
+
+func TestWithPlatform(t *testing.T) {
+	cluster, err := fake.NewCluster(fake.WithPlatform(k8s.Platform{Name: "eks", Version: "1.29"}))
+	require.NoError(t, err)
+	assert.Equal(t, k8s.Platform{Name: "eks", Version: "1.29"}, cluster.Platform())
+}
+
+func TestWithForbidden(t *testing.T) {
+	cluster, err := fake.NewCluster(fake.WithYAML(objects), fake.WithForbidden("deployments", "nodes"))
+	require.NoError(t, err)
+
+	_, err = cluster.GetK8sClient().CoreV1().Nodes().List(context.Background(), metav1.ListOptions{})
+	assert.True(t, k8sapierror.IsForbidden(err))
+	_, err = cluster.GetMetadataClient().Resource(schema.GroupVersionResource{Version: "v1", Resource: "nodes"}).
+		List(context.Background(), metav1.ListOptions{})
+	assert.True(t, k8sapierror.IsForbidden(err))
+
+	artifactList, err := trivyk8s.New(cluster).Namespace("app").ListArtifacts(context.Background())
+	require.NoError(t, err)
+	require.Len(t, artifactList, 1)
+	assert.Equal(t, "ServiceAccount", artifactList[0].Kind)
+}
+
+func TestJobCollection(t *testing.T) {
+	cluster, err := fake.NewCluster(fake.WithYAML(objects))
+	require.NoError(t, err)
+
+	collector := jobs.NewCollector(cluster,
+		jobs.WithJobTemplateName("node-collector"),
+		jobs.WithJobNamespace("trivy-temp"),
+		jobs.WithCommandsPath([]string{"./testdata"}),
+		jobs.WithTimetout(10*time.Second),
+	)
+	output, err := collector.ApplyAndCollect(context.Background(), "node-1")
+	require.NoError(t, err)
+	assert.Equal(t, "fake logs", output)
+}
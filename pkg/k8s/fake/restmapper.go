@@ -0,0 +1,175 @@
+package fake
+
+import (
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/utils/strings/slices"
+)
+
+// deprecated groups whose kinds are served by other groups
+var skippedGroups = []string{"extensions"}
+
+var clusterScopedKinds = []string{
+	"APIService",
+	"CertificateSigningRequest",
+	"ClusterRole",
+	"ClusterRoleBinding",
+	"ClusterTrustBundle",
+	"ComponentStatus",
+	"CSIDriver",
+	"CSINode",
+	"CustomResourceDefinition",
+	"DeviceClass",
+	"FlowSchema",
+	"IngressClass",
+	"IPAddress",
+	"MutatingAdmissionPolicy",
+	"MutatingAdmissionPolicyBinding",
+	"MutatingWebhookConfiguration",
+	"Namespace",
+	"Node",
+	"PersistentVolume",
+	"PriorityClass",
+	"PriorityLevelConfiguration",
+	"RuntimeClass",
+	"SelfSubjectAccessReview",
+	"SelfSubjectReview",
+	"SelfSubjectRulesReview",
+	"ServiceCIDR",
+	"StorageClass",
+	"StorageVersion",
+	"StorageVersionMigration",
+	"SubjectAccessReview",
+	"TokenReview",
+	"ValidatingAdmissionPolicy",
+	"ValidatingAdmissionPolicyBinding",
+	"ValidatingWebhookConfiguration",
+	"VolumeAttachment",
+	"VolumeAttributesClass",
+}
+
+// apiGroupResources returns the resources of the preferred version of every
+// built-in group and of the seeded objects kinds. Core comes first so that it
+// wins resources served by several groups, such as events
+func apiGroupResources(objects []runtime.Object) []*restmapper.APIGroupResources {
+	groups := make(map[string]*restmapper.APIGroupResources)
+	add := func(gvk schema.GroupVersionKind, namespaced bool) {
+		group, ok := groups[gvk.Group]
+		if !ok {
+			gv := metav1.GroupVersionForDiscovery{GroupVersion: gvk.GroupVersion().String(), Version: gvk.Version}
+			group = &restmapper.APIGroupResources{
+				Group: metav1.APIGroup{
+					Name:             gvk.Group,
+					Versions:         []metav1.GroupVersionForDiscovery{gv},
+					PreferredVersion: gv,
+				},
+				VersionedResources: make(map[string][]metav1.APIResource),
+			}
+			groups[gvk.Group] = group
+		}
+		if _, ok := group.VersionedResources[gvk.Version]; !ok && gvk.Version != group.Group.PreferredVersion.Version {
+			group.Group.Versions = append(group.Group.Versions, metav1.GroupVersionForDiscovery{
+				GroupVersion: gvk.GroupVersion().String(),
+				Version:      gvk.Version,
+			})
+		}
+		plural, singular := meta.UnsafeGuessKindToResource(gvk)
+		for _, r := range group.VersionedResources[gvk.Version] {
+			if r.Kind == gvk.Kind {
+				return
+			}
+		}
+		group.VersionedResources[gvk.Version] = append(group.VersionedResources[gvk.Version], metav1.APIResource{
+			Name:         plural.Resource,
+			SingularName: singular.Resource,
+			Namespaced:   namespaced,
+			Kind:         gvk.Kind,
+			Verbs:        metav1.Verbs{"get", "list", "watch", "create", "update", "patch", "delete"},
+		})
+	}
+
+	for _, gv := range preferredVersions() {
+		kinds := make([]string, 0)
+		for kind := range scheme.Scheme.KnownTypes(gv) {
+			kinds = append(kinds, kind)
+		}
+		sort.Strings(kinds)
+		for _, kind := range kinds {
+			gvk := gv.WithKind(kind)
+			obj, err := scheme.Scheme.New(gvk)
+			if err != nil {
+				continue
+			}
+			// only objects are resources, skip lists and options
+			if _, err := meta.Accessor(obj); err != nil || meta.IsListType(obj) {
+				continue
+			}
+			add(gvk, !slices.Contains(clusterScopedKinds, kind))
+		}
+	}
+	for _, obj := range objects {
+		accessor, err := meta.Accessor(obj)
+		if err != nil {
+			continue
+		}
+		gvk := obj.GetObjectKind().GroupVersionKind()
+		if !scheme.Scheme.Recognizes(gvk) {
+			add(gvk, accessor.GetNamespace() != "")
+		}
+	}
+
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	result := make([]*restmapper.APIGroupResources, 0, len(groups))
+	for _, name := range names {
+		result = append(result, groups[name])
+	}
+	return result
+}
+
+// preferredVersions returns the first stable, or else the first, version of
+// every built-in group
+func preferredVersions() []schema.GroupVersion {
+	versions := make(map[string][]schema.GroupVersion)
+	for _, gv := range scheme.Scheme.PrioritizedVersionsAllGroups() {
+		if gv.Version == runtime.APIVersionInternal || slices.Contains(skippedGroups, gv.Group) {
+			continue
+		}
+		versions[gv.Group] = append(versions[gv.Group], gv)
+	}
+	preferred := make([]schema.GroupVersion, 0, len(versions))
+	for _, gvs := range versions {
+		p := gvs[0]
+		for _, gv := range gvs {
+			if !strings.Contains(gv.Version, "alpha") && !strings.Contains(gv.Version, "beta") {
+				p = gv
+				break
+			}
+		}
+		preferred = append(preferred, p)
+	}
+	return preferred
+}
+
+func apiResourceLists(groups []*restmapper.APIGroupResources) []*metav1.APIResourceList {
+	lists := make([]*metav1.APIResourceList, 0)
+	for _, group := range groups {
+		for _, v := range group.Group.Versions {
+			lists = append(lists, &metav1.APIResourceList{
+				GroupVersion: v.GroupVersion,
+				APIResources: group.VersionedResources[v.Version],
+			})
+		}
+	}
+	return lists
+}
@@ -0,0 +1,198 @@
+package k8s
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+
+	k8sapierror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/strings/slices"
+	"sigs.k8s.io/yaml"
+
+	"github.com/aquasecurity/trivy-kubernetes/pkg/bom"
+)
+
+const (
+	// HelmReleaseComponentType is the Type property of BOM components built from helm releases
+	HelmReleaseComponentType = "helmRelease"
+
+	helmReleaseSecretType = "helm.sh/release.v1"
+	// the releases stored by helm, only their deployed revision
+	helmReleaseSelector = "owner=helm,status=deployed"
+)
+
+var gzipMagic = []byte{0x1f, 0x8b, 0x08}
+
+// kinds helm installs without a namespace
+var clusterScopedKinds = []string{
+	"Namespace",
+	"Node",
+	"PersistentVolume",
+	"ClusterRole",
+	"ClusterRoleBinding",
+	"CustomResourceDefinition",
+	"StorageClass",
+	"PriorityClass",
+	"IngressClass",
+	"RuntimeClass",
+	"APIService",
+	"ValidatingWebhookConfiguration",
+	"MutatingWebhookConfiguration",
+}
+
+type helmRelease struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Version   int    `json:"version"`
+	Manifest  string `json:"manifest"`
+	Info      struct {
+		Status string `json:"status"`
+	} `json:"info"`
+	Chart struct {
+		Metadata struct {
+			Name       string `json:"name"`
+			Version    string `json:"version"`
+			AppVersion string `json:"appVersion"`
+		} `json:"metadata"`
+	} `json:"chart"`
+}
+
+// collectHelmReleases returns a BOM component for the deployed revision of every
+// helm v3 release stored in the namespace secrets
+func (c *cluster) collectHelmReleases(ctx context.Context, namespace string) ([]bom.Component, error) {
+	secrets, err := c.clientset.CoreV1().Secrets(namespace).List(ctx, metav1.ListOptions{LabelSelector: helmReleaseSelector})
+	if err != nil {
+		if k8sapierror.IsNotFound(err) || k8sapierror.IsForbidden(err) {
+			slog.Warn("Unable to list helm releases", "error", err)
+			return []bom.Component{}, nil
+		}
+		return nil, err
+	}
+	latest := make(map[string]*helmRelease)
+	for _, secret := range secrets.Items {
+		if secret.Type != helmReleaseSecretType {
+			continue
+		}
+		release, err := decodeHelmRelease(secret.Data["release"])
+		if err != nil {
+			slog.Warn("Unable to decode helm release", "secret", secret.Namespace+"/"+secret.Name, "error", err)
+			continue
+		}
+		if release.Namespace == "" {
+			release.Namespace = secret.Namespace
+		}
+		key := release.Namespace + "/" + release.Name
+		if r, ok := latest[key]; !ok || r.Version < release.Version {
+			latest[key] = release
+		}
+	}
+
+	components := make([]bom.Component, 0, len(latest))
+	for _, release := range latest {
+		components = append(components, helmReleaseComponent(release))
+	}
+	sort.Slice(components, func(i, j int) bool {
+		if components[i].Namespace != components[j].Namespace {
+			return components[i].Namespace < components[j].Namespace
+		}
+		return components[i].Properties["Name"] < components[j].Properties["Name"]
+	})
+	return components, nil
+}
+
+// decodeHelmRelease decodes the release stored by helm v3: a base64 encoded,
+// usually gzip compressed, JSON document
+func decodeHelmRelease(data []byte) (*helmRelease, error) {
+	b, err := base64.StdEncoding.DecodeString(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("decoding base64 release: %w", err)
+	}
+	if bytes.HasPrefix(b, gzipMagic) {
+		r, err := gzip.NewReader(bytes.NewReader(b))
+		if err != nil {
+			return nil, fmt.Errorf("decompressing release: %w", err)
+		}
+		defer r.Close()
+		if b, err = io.ReadAll(r); err != nil {
+			return nil, fmt.Errorf("decompressing release: %w", err)
+		}
+	}
+	var release helmRelease
+	if err := json.Unmarshal(b, &release); err != nil {
+		return nil, fmt.Errorf("unsupported release encoding: %w", err)
+	}
+	return &release, nil
+}
+
+func helmReleaseComponent(release *helmRelease) bom.Component {
+	return bom.Component{
+		Namespace: release.Namespace,
+		Name:      release.Chart.Metadata.Name,
+		Version:   release.Chart.Metadata.Version,
+		Properties: map[string]string{
+			"Name":       release.Name,
+			"Type":       HelmReleaseComponentType,
+			"AppVersion": release.Chart.Metadata.AppVersion,
+			"Revision":   strconv.Itoa(release.Version),
+			"Status":     release.Info.Status,
+		},
+		Resources: manifestResources(release.Manifest, release.Namespace),
+	}
+}
+
+// manifestResources returns the resources rendered in a release manifest,
+// namespaced resources without namespace are installed in the release namespace
+func manifestResources(manifest, namespace string) []bom.Resource {
+	resources := make([]bom.Resource, 0)
+	for _, doc := range splitManifest(manifest) {
+		var obj struct {
+			Kind     string `json:"kind"`
+			Metadata struct {
+				Name      string `json:"name"`
+				Namespace string `json:"namespace"`
+			} `json:"metadata"`
+		}
+		if err := yaml.Unmarshal([]byte(doc), &obj); err != nil || obj.Kind == "" {
+			continue
+		}
+		ns := obj.Metadata.Namespace
+		if ns == "" && !slices.Contains(clusterScopedKinds, obj.Kind) {
+			ns = namespace
+		}
+		resources = append(resources, bom.Resource{
+			Kind:      obj.Kind,
+			Namespace: ns,
+			Name:      obj.Metadata.Name,
+		})
+	}
+	return resources
+}
+
+func splitManifest(manifest string) []string {
+	docs := make([]string, 0)
+	var doc strings.Builder
+	for _, line := range strings.Split(manifest, "\n") {
+		if strings.HasPrefix(line, "---") {
+			docs = append(docs, doc.String())
+			doc.Reset()
+			continue
+		}
+		doc.WriteString(line)
+		doc.WriteString("\n")
+	}
+	return append(docs, doc.String())
+}
+
+// IsHelmRelease returns true if the BOM component describes a helm release
+func IsHelmRelease(component bom.Component) bool {
+	return component.Properties["Type"] == HelmReleaseComponentType
+}
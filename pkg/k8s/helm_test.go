@@ -0,0 +1,111 @@
+package k8s
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/aquasecurity/trivy-kubernetes/pkg/bom"
+)
+
+const helmReleaseJSON = `{
+  "name": "web",
+  "namespace": "apps",
+  "version": 3,
+  "info": {"status": "deployed"},
+  "chart": {"metadata": {"name": "nginx", "version": "15.1.0", "appVersion": "1.25.1"}},
+  "manifest": "---\n# Source: nginx/templates/sa.yaml\napiVersion: v1\nkind: ServiceAccount\nmetadata:\n  name: web-nginx\n---\n# Source: nginx/templates/clusterrole.yaml\napiVersion: rbac.authorization.k8s.io/v1\nkind: ClusterRole\nmetadata:\n  name: web-nginx\n---\n# Source: nginx/templates/deployment.yaml\napiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web-nginx\n  namespace: apps\n"
+}`
+
+func TestDecodeHelmRelease(t *testing.T) {
+	var compressed bytes.Buffer
+	w := gzip.NewWriter(&compressed)
+	_, err := w.Write([]byte(helmReleaseJSON))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr string
+	}{
+		{
+			name: "gzip compressed release",
+			data: compressed.Bytes(),
+		},
+		{
+			name: "uncompressed release",
+			data: []byte(helmReleaseJSON),
+		},
+		{
+			name:    "unsupported encoding",
+			data:    []byte{0x0a, 0x03, 0x77, 0x65, 0x62},
+			wantErr: "unsupported release encoding",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			release, err := decodeHelmRelease([]byte(base64.StdEncoding.EncodeToString(test.data)))
+			if test.wantErr != "" {
+				assert.ErrorContains(t, err, test.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, bom.Component{
+				Namespace: "apps",
+				Name:      "nginx",
+				Version:   "15.1.0",
+				Properties: map[string]string{
+					"Name":       "web",
+					"Type":       HelmReleaseComponentType,
+					"AppVersion": "1.25.1",
+					"Revision":   "3",
+					"Status":     "deployed",
+				},
+				Resources: []bom.Resource{
+					{Kind: "ServiceAccount", Namespace: "apps", Name: "web-nginx"},
+					{Kind: "ClusterRole", Name: "web-nginx"},
+					{Kind: "Deployment", Namespace: "apps", Name: "web-nginx"},
+				},
+			}, helmReleaseComponent(release))
+		})
+	}
+}
+
+func TestCollectHelmReleasesDeployedOnly(t *testing.T) {
+	releaseSecret := func(version, status string) *corev1.Secret {
+		release := strings.Replace(helmReleaseJSON, `"version": 3`, `"version": `+version, 1)
+		release = strings.Replace(release, `"status": "deployed"`, `"status": "`+status+`"`, 1)
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "sh.helm.release.v1.web.v" + version,
+				Namespace: "apps",
+				Labels:    map[string]string{"owner": "helm", "name": "web", "status": status, "version": version},
+			},
+			Type: helmReleaseSecretType,
+			Data: map[string][]byte{"release": []byte(base64.StdEncoding.EncodeToString([]byte(release)))},
+		}
+	}
+	clientset := fake.NewClientset(
+		releaseSecret("2", "superseded"),
+		releaseSecret("3", "deployed"),
+		releaseSecret("4", "failed"),
+	)
+	c, err := NewCluster(clientset, nil, nil)
+	require.NoError(t, err)
+
+	components, err := c.(*cluster).collectHelmReleases(context.Background(), "apps")
+	require.NoError(t, err)
+	require.Len(t, components, 1)
+	assert.Equal(t, "3", components[0].Properties["Revision"])
+	assert.Equal(t, "deployed", components[0].Properties["Status"])
+}
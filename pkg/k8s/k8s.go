@@ -2,8 +2,11 @@ package k8s
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"os"
+	"sort"
 	"strings"
 
 	containerimage "github.com/google/go-containerregistry/pkg/name"
@@ -14,12 +17,18 @@ import (
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/discovery/cached/memory"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/metadata"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
 	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/pager"
 	"k8s.io/utils/strings/slices"
 
 	"github.com/aquasecurity/trivy-kubernetes/pkg/bom"
@@ -27,13 +36,23 @@ import (
 	"github.com/aquasecurity/trivy-kubernetes/utils"
 )
 
+var serviceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
 var (
+	// UpstreamOrgName lists the component names of the upstream organizations, read
+	// by every DefaultComponentCatalog call.
+	//
+	// Deprecated: set the org of the rules of a ComponentCatalog
 	UpstreamOrgName = map[string]string{
 		"k8s.io":      "controller-manager,kubelet,apiserver,kubectl,kubernetes,kube-scheduler,kube-proxy,cloud-provider,ingress-nginx",
 		"sigs.k8s.io": "secrets-store-csi-driver",
 		"go.etcd.io":  "etcd/v3",
 	}
 
+	// UpstreamRepoName maps the values of the component label of pods to component
+	// names, read by every DefaultComponentCatalog call.
+	//
+	// Deprecated: add rules matching the pods to a ComponentCatalog
 	UpstreamRepoName = map[string]string{
 		"kube-controller-manager":  "controller-manager",
 		"kubelet":                  "kubelet",
@@ -47,6 +66,10 @@ var (
 		"cloud-controller-manager": "cloud-provider",
 		"secrets-store-csi-driver": "secrets-store-csi-driver",
 	}
+	// CoreComponentPropertyType maps component names to their type, read by every
+	// DefaultComponentCatalog call.
+	//
+	// Deprecated: set the type of the rules of a ComponentCatalog
 	CoreComponentPropertyType = map[string]string{
 		"controller-manager": "controlPlane",
 		"apiserver":          "controlPlane",
@@ -88,7 +111,12 @@ const (
 	ClusterRoleBindings    = "clusterrolebindings"
 	Nodes                  = "nodes"
 	k8sComponentNamespace  = "kube-system"
+	defaultClusterName     = "k8s.io/kubernetes"
 	serviceAccountDefault  = "default"
+	defaultNamespace       = "default"
+	kubernetesService      = "kubernetes"
+	kubernetesOrg          = "k8s.io"
+	componentPodsPageSize  = 500
 
 	native   = "k8s"
 	gke      = "gke"
@@ -108,35 +136,47 @@ type Cluster interface {
 	GetCurrentNamespace() string
 	// GetDynamicClient returns a dynamic k8s client
 	GetDynamicClient() dynamic.Interface
-	// GetK8sClientSet returns a k8s client set
+	// GetK8sClientSet returns a k8s client set, nil if the cluster was not created from a rest config
+	//
+	// Deprecated: use GetK8sClient
 	GetK8sClientSet() *kubernetes.Clientset
+	// GetK8sClient returns a k8s client
+	GetK8sClient() kubernetes.Interface
+	// GetMetadataClient returns a client of the objects metadata, nil if the cluster
+	// was not created from a rest config
+	GetMetadataClient() metadata.Interface
 	// GetGVRs returns cluster GroupVersionResource to query kubernetes, receives
 	// a boolean to determine if returns namespaced GVRs only or all GVRs, unless
-	// resources is passed to filter
+	// resources is passed to filter. Resources the cluster doesn't serve are skipped
 	GetGVRs(bool, []string) ([]schema.GroupVersionResource, error)
 	// GetGVR returns resource GroupVersionResource to query kubernetes, receives
-	// a string with the resource kind
+	// a string with the resource or kind, optionally qualified as resource.group
 	GetGVR(string) (schema.GroupVersionResource, error)
 	// CreateBomComponents returns a list of BOM components by a namespace
-	CreateBomComponents(ctx context.Context, namespace string) ([]bom.Component, error)
+	CreateBomComponents(ctx context.Context, namespace string, opts ...BomOption) ([]bom.Component, error)
 	// CreateClusterBom returns KBOM for a cluster
-	CreateClusterBom(ctx context.Context) (*bom.Result, error)
+	CreateClusterBom(ctx context.Context, opts ...BomOption) (*bom.Result, error)
 	// GetClusterVersion return cluster git version
 	GetClusterVersion() string
-	// AuthByResource return image pull secrets by resource pod spec
-	AuthByResource(resource unstructured.Unstructured) (map[string]docker.Auth, error)
-	// SpecByPlatform return spec by platform type and version
+	// AuthByResource return image pull secrets by resource pod spec, the credentials
+	// are read through the cache of the scan when given, one by one otherwise
+	AuthByResource(resource unstructured.Unstructured, opts ...AuthOption) (map[string]docker.Auth, error)
+	// Platform returns the detected cluster platform
 	Platform() Platform
+	// GetClusterID returns an identifier of the cluster which is the same for every
+	// kubeconfig and tool reaching it
+	GetClusterID(ctx context.Context) (string, error)
 }
 
 type cluster struct {
 	currentContext   string
 	currentNamespace string
 	serverVersion    string
+	clusterName      string
 	dynamicClient    dynamic.Interface
+	metadataClient   metadata.Interface
 	restMapper       meta.RESTMapper
-	clientset        *kubernetes.Clientset
-	cConfig          clientcmd.ClientConfig
+	clientset        kubernetes.Interface
 }
 
 type ClusterOption func(*genericclioptions.ConfigFlags)
@@ -172,6 +212,149 @@ func WithBurst(burst int) ClusterOption {
 	}
 }
 
+// WithImpersonate sends the requests as the user, with its groups and UID when given
+func WithImpersonate(user string, groups ...string) ClusterOption {
+	return func(c *genericclioptions.ConfigFlags) {
+		c.Impersonate = &user
+		if len(groups) > 0 {
+			c.ImpersonateGroup = &groups
+		}
+	}
+}
+
+// WithImpersonateUID sets the UID of the impersonated user
+func WithImpersonateUID(uid string) ClusterOption {
+	return func(c *genericclioptions.ConfigFlags) {
+		c.ImpersonateUID = &uid
+	}
+}
+
+// WithToken authenticates with the bearer token instead of the kubeconfig credentials
+func WithToken(token string) ClusterOption {
+	return func(c *genericclioptions.ConfigFlags) {
+		c.BearerToken = &token
+	}
+}
+
+// WithTokenFile authenticates with the bearer token of the file, which is read
+// again when it changes, such as a projected service account token
+func WithTokenFile(tokenFile string) ClusterOption {
+	return func(o *genericclioptions.ConfigFlags) {
+		o.WrapConfigFn = combineConfigFns(o.WrapConfigFn, func(c *rest.Config) *rest.Config {
+			c.BearerTokenFile = tokenFile
+			// the token of the kubeconfig would take precedence over the file
+			c.BearerToken = ""
+			return c
+		})
+	}
+}
+
+// WithServer sets the api server URL instead of the kubeconfig one
+func WithServer(server string) ClusterOption {
+	return func(c *genericclioptions.ConfigFlags) {
+		c.APIServer = &server
+	}
+}
+
+// WithCAFile sets the certificate authority file verifying the api server certificate
+func WithCAFile(caFile string) ClusterOption {
+	return func(c *genericclioptions.ConfigFlags) {
+		c.CAFile = &caFile
+	}
+}
+
+// WithClientCertificate authenticates with the client certificate and key files
+func WithClientCertificate(certFile, keyFile string) ClusterOption {
+	return func(c *genericclioptions.ConfigFlags) {
+		c.CertFile = &certFile
+		c.KeyFile = &keyFile
+	}
+}
+
+// WithClusterName names the cluster of a rest config, such as the in-cluster one.
+// With a kubeconfig it selects the kubeconfig cluster, as kubectl --cluster does
+func WithClusterName(name string) ClusterOption {
+	return func(c *genericclioptions.ConfigFlags) {
+		c.ClusterName = &name
+	}
+}
+
+// WithTLSServerName sets the server name verifying the api server certificate
+func WithTLSServerName(serverName string) ClusterOption {
+	return func(c *genericclioptions.ConfigFlags) {
+		c.TLSServerName = &serverName
+	}
+}
+
+// validateClusterOptions returns an error for the credential options which can't
+// be used together
+func validateClusterOptions(cf *genericclioptions.ConfigFlags) error {
+	if (flagValue(cf.CertFile) == "") != (flagValue(cf.KeyFile) == "") {
+		return errors.New("client certificate and key must be set together")
+	}
+	if flagValue(cf.BearerToken) != "" && flagValue(cf.CertFile) != "" {
+		return errors.New("token and client certificate options are mutually exclusive")
+	}
+	impersonateGroups := cf.ImpersonateGroup != nil && len(*cf.ImpersonateGroup) > 0
+	if flagValue(cf.Impersonate) == "" && (impersonateGroups || flagValue(cf.ImpersonateUID) != "") {
+		return errors.New("impersonating groups or a UID requires impersonating a user")
+	}
+	return nil
+}
+
+// validateTokenOptions returns an error when both WithToken and WithTokenFile are
+// given. WithTokenFile clears the token of the config it wraps, so a config with
+// a token file and no token comes from it
+func validateTokenOptions(cf *genericclioptions.ConfigFlags, config *rest.Config) error {
+	if flagValue(cf.BearerToken) != "" && config.BearerTokenFile != "" && config.BearerToken == "" {
+		return errors.New("token and token file options are mutually exclusive")
+	}
+	return nil
+}
+
+// applyConfigFlags sets the server and credential options on a rest config, as
+// the kubeconfig loading does
+func applyConfigFlags(config *rest.Config, cf *genericclioptions.ConfigFlags) {
+	if server := flagValue(cf.APIServer); server != "" {
+		config.Host = server
+	}
+	if serverName := flagValue(cf.TLSServerName); serverName != "" {
+		config.TLSClientConfig.ServerName = serverName
+	}
+	if caFile := flagValue(cf.CAFile); caFile != "" {
+		config.TLSClientConfig.CAFile = caFile
+		config.TLSClientConfig.CAData = nil
+	}
+	if certFile := flagValue(cf.CertFile); certFile != "" {
+		config.TLSClientConfig.CertFile = certFile
+		config.TLSClientConfig.CertData = nil
+	}
+	if keyFile := flagValue(cf.KeyFile); keyFile != "" {
+		config.TLSClientConfig.KeyFile = keyFile
+		config.TLSClientConfig.KeyData = nil
+	}
+	if token := flagValue(cf.BearerToken); token != "" {
+		config.BearerToken = token
+		config.BearerTokenFile = ""
+	}
+	if user := flagValue(cf.Impersonate); user != "" {
+		config.Impersonate.UserName = user
+	}
+	if uid := flagValue(cf.ImpersonateUID); uid != "" {
+		config.Impersonate.UID = uid
+	}
+	if cf.ImpersonateGroup != nil && len(*cf.ImpersonateGroup) > 0 {
+		config.Impersonate.Groups = *cf.ImpersonateGroup
+	}
+}
+
+func flagValue(flag *string) string {
+	if flag == nil {
+		return ""
+	}
+	return *flag
+}
+
 // Helper function to combine multiple config functions
 func combineConfigFns(existing, newFn func(*rest.Config) *rest.Config) func(*rest.Config) *rest.Config {
 	if existing == nil {
@@ -185,12 +368,18 @@ func combineConfigFns(existing, newFn func(*rest.Config) *rest.Config) func(*res
 	}
 }
 
-// GetCluster returns a current configured cluster,
+// GetCluster returns a current configured cluster. Its requests are retried
+// with the DefaultRetryPolicy unless WithRetryPolicy is given, so a throttled or
+// unavailable api server delays the calls by up to a few minutes instead of
+// failing them, WithRetryPolicy(RetryPolicy{MaxAttempts: 1}) disables the retries
 func GetCluster(opts ...ClusterOption) (Cluster, error) {
 	cf := genericclioptions.NewConfigFlags(true)
-	for _, opt := range opts {
+	for _, opt := range append([]ClusterOption{WithRetryPolicy(DefaultRetryPolicy())}, opts...) {
 		opt(cf)
 	}
+	if err := validateClusterOptions(cf); err != nil {
+		return nil, err
+	}
 
 	// disable warnings
 	rest.SetDefaultWarningHandler(rest.NoWarnings{})
@@ -206,8 +395,49 @@ func GetCluster(opts ...ClusterOption) (Cluster, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := validateTokenOptions(cf, kubeConfig); err != nil {
+		return nil, err
+	}
 
-	return getCluster(clientConfig, kubeConfig, restMapper, *cf.Context, false)
+	c, err := getCluster(clientConfig, kubeConfig, restMapper, *cf.Context, false)
+	if err != nil {
+		return nil, err
+	}
+	if name := flagValue(cf.ClusterName); name != "" {
+		c.clusterName = name
+	}
+	return c, nil
+}
+
+// KubeConfigContext is a context of the kubeconfig
+type KubeConfigContext struct {
+	Name      string
+	Cluster   string
+	Namespace string
+}
+
+// GetKubeConfigContexts returns the contexts of the kubeconfig sorted by name
+func GetKubeConfigContexts(opts ...ClusterOption) ([]KubeConfigContext, error) {
+	cf := genericclioptions.NewConfigFlags(true)
+	for _, opt := range opts {
+		opt(cf)
+	}
+	rawCfg, err := cf.ToRawKubeConfigLoader().RawConfig()
+	if err != nil {
+		return nil, fmt.Errorf("loading kubeconfig: %w", err)
+	}
+	contexts := make([]KubeConfigContext, 0, len(rawCfg.Contexts))
+	for name, context := range rawCfg.Contexts {
+		contexts = append(contexts, KubeConfigContext{
+			Name:      name,
+			Cluster:   context.Cluster,
+			Namespace: context.Namespace,
+		})
+	}
+	sort.Slice(contexts, func(i, j int) bool {
+		return contexts[i].Name < contexts[j].Name
+	})
+	return contexts, nil
 }
 
 func getCluster(clientConfig clientcmd.ClientConfig, kubeConfig *rest.Config, restMapper meta.RESTMapper, currentContext string, fakeConfig bool) (*cluster, error) {
@@ -222,18 +452,24 @@ func getCluster(clientConfig clientcmd.ClientConfig, kubeConfig *rest.Config, re
 	if err != nil {
 		return nil, err
 	}
+	metadataClient, err := metadata.NewForConfig(kubeConfig)
+	if err != nil {
+		return nil, err
+	}
 	rawCfg, err := clientConfig.RawConfig()
 	if err != nil {
 		return nil, err
 	}
 
 	var namespace string
+	clusterName := defaultClusterName
 
 	if len(currentContext) == 0 {
 		currentContext = rawCfg.CurrentContext
 	}
 	if context, ok := rawCfg.Contexts[currentContext]; ok {
 		namespace = context.Namespace
+		clusterName = context.Cluster
 	}
 
 	if len(namespace) == 0 {
@@ -250,14 +486,111 @@ func getCluster(clientConfig clientcmd.ClientConfig, kubeConfig *rest.Config, re
 	return &cluster{
 		currentContext:   currentContext,
 		currentNamespace: namespace,
+		clusterName:      clusterName,
 		dynamicClient:    k8sDynamicClient,
+		metadataClient:   metadataClient,
 		restMapper:       restMapper,
 		clientset:        kubeClientset,
-		cConfig:          clientConfig,
 		serverVersion:    serverVersion,
 	}, nil
 }
 
+// NewCluster returns a cluster using the given clients, such as client-go fakes.
+// When restMapper is nil it is built from the clientset discovery
+func NewCluster(clientset kubernetes.Interface, dynamicClient dynamic.Interface, restMapper meta.RESTMapper) (Cluster, error) {
+	return newCluster(clientset, dynamicClient, restMapper, "default", defaultClusterName)
+}
+
+// GetClusterFromRESTConfig returns a cluster for a rest config, such as the one
+// of an operator. The current namespace is the service account namespace when
+// running in a pod, the cluster name is set by WithClusterName. Server and credential
+// options override the config, options which configure the kubeconfig loading,
+// like WithContext, are ignored. The requests are retried as the GetCluster ones
+func GetClusterFromRESTConfig(config *rest.Config, opts ...ClusterOption) (Cluster, error) {
+	cf := genericclioptions.NewConfigFlags(true)
+	for _, opt := range append([]ClusterOption{WithRetryPolicy(DefaultRetryPolicy())}, opts...) {
+		opt(cf)
+	}
+	if err := validateClusterOptions(cf); err != nil {
+		return nil, err
+	}
+	config = rest.CopyConfig(config)
+	applyConfigFlags(config, cf)
+	if cf.WrapConfigFn != nil {
+		config = cf.WrapConfigFn(config)
+	}
+	if err := validateTokenOptions(cf, config); err != nil {
+		return nil, err
+	}
+
+	// disable warnings
+	rest.SetDefaultWarningHandler(rest.NoWarnings{})
+
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	metadataClient, err := metadata.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	clusterName := defaultClusterName
+	if name := flagValue(cf.ClusterName); name != "" {
+		clusterName = name
+	}
+	c, err := newCluster(clientset, dynamicClient, nil, serviceAccountNamespace(), clusterName)
+	if err != nil {
+		return nil, err
+	}
+	c.metadataClient = metadataClient
+	return c, nil
+}
+
+// GetInClusterCluster returns a cluster using the service account credentials
+// mounted in the pod
+func GetInClusterCluster(opts ...ClusterOption) (Cluster, error) {
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, fmt.Errorf("loading in-cluster config: %w", err)
+	}
+	return GetClusterFromRESTConfig(config, opts...)
+}
+
+func newCluster(clientset kubernetes.Interface, dynamicClient dynamic.Interface, restMapper meta.RESTMapper, namespace, clusterName string) (*cluster, error) {
+	sv, err := clientset.Discovery().ServerVersion()
+	if err != nil {
+		return nil, fmt.Errorf("getting server version: %w", err)
+	}
+	if restMapper == nil {
+		restMapper = restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(clientset.Discovery()))
+	}
+	return &cluster{
+		currentNamespace: namespace,
+		clusterName:      clusterName,
+		dynamicClient:    dynamicClient,
+		restMapper:       restMapper,
+		clientset:        clientset,
+		serverVersion:    strings.TrimPrefix(sv.GitVersion, "v"),
+	}, nil
+}
+
+// serviceAccountNamespace returns the namespace of the pod service account,
+// default outside of a pod
+func serviceAccountNamespace() string {
+	data, err := os.ReadFile(serviceAccountNamespaceFile)
+	if err != nil {
+		return "default"
+	}
+	if namespace := strings.TrimSpace(string(data)); namespace != "" {
+		return namespace
+	}
+	return "default"
+}
+
 // GetCurrentContext returns local kubernetes current-context
 func (c *cluster) GetCurrentContext() string {
 	return c.currentContext
@@ -268,6 +601,31 @@ func (c *cluster) GetClusterVersion() string {
 	return c.serverVersion
 }
 
+// GetClusterID returns the UID of the kube-system namespace, falling back to the
+// UID of the default namespace and of the kubernetes service when it can't be
+// read. The UID is prefixed with its source, kube-system:, default: or service:,
+// so IDs read with different permissions never collide
+func (c *cluster) GetClusterID(ctx context.Context) (string, error) {
+	var errs []error
+	for _, ns := range []string{k8sComponentNamespace, defaultNamespace} {
+		namespace, err := c.clientset.CoreV1().Namespaces().Get(ctx, ns, metav1.GetOptions{})
+		if err == nil && namespace.UID != "" {
+			return ns + ":" + string(namespace.UID), nil
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("namespace %s: %w", ns, err))
+		}
+	}
+	service, err := c.clientset.CoreV1().Services(defaultNamespace).Get(ctx, kubernetesService, metav1.GetOptions{})
+	if err == nil && service.UID != "" {
+		return "service:" + string(service.UID), nil
+	}
+	if err != nil {
+		errs = append(errs, fmt.Errorf("service %s: %w", kubernetesService, err))
+	}
+	return "", fmt.Errorf("unable to identify cluster: %w", errors.Join(errs...))
+}
+
 // GetCurrentNamespace returns local kubernetes current namespace
 func (c *cluster) GetCurrentNamespace() string {
 	return c.currentNamespace
@@ -280,83 +638,23 @@ func (c *cluster) GetDynamicClient() dynamic.Interface {
 
 // GetK8sClientSet returns k8s clientSet
 func (c *cluster) GetK8sClientSet() *kubernetes.Clientset {
-	return c.clientset
-}
-
-// GetK8sClientSet returns k8s clientSet
-func (c *cluster) Platform() Platform {
-	platform, err := c.Platfrom()
-	if err != nil {
-		return Platform{Name: "k8s", Version: "1.23.0"}
-	}
-	return platform
+	clientset, _ := c.clientset.(*kubernetes.Clientset)
+	return clientset
 }
 
-func (cluster *cluster) Platfrom() (Platform, error) {
-	v := cluster.getOpenShiftVersion(context.Background())
-	if len(v) != 0 {
-		return Platform{Name: "ocp", Version: majorVersion(v)}, nil
-	}
-	nodeName := cluster.getNodeName()
-	semVersion, err := cluster.clientset.ServerVersion()
-	if err != nil {
-		return Platform{}, err
-	}
-	p := getPlatformInfoFromVersion(semVersion.GitVersion)
-	var name string
-	switch {
-	case strings.Contains(p.Version, k3s):
-		name = k3s
-	case strings.Contains(p.Version, rke2):
-		name = rke2
-	case strings.Contains(p.Version, microk8s):
-		name = microk8s
-	case strings.Contains(nodeName, aks):
-		name = aks
-	case strings.Contains(nodeName, eks):
-		name = eks
-	case strings.Contains(nodeName, gke):
-		name = gke
-	default:
-		name = "k8s"
-	}
-	return Platform{Name: name, Version: p.Version}, nil
-}
-
-type Platform struct {
-	Name    string
-	Version string
-}
-
-func (cluster *cluster) getOpenShiftVersion(ctx context.Context) string {
-	gvr, err := cluster.restMapper.ResourceFor(schema.GroupVersionResource{Resource: "clusterversions"})
-	if err != nil {
-		return ""
-	}
-	dclient := cluster.dynamicClient.Resource(gvr).Namespace("")
-	resources, err := dclient.List(ctx, metav1.ListOptions{})
-	if err != nil {
-		return ""
-	}
-	var version string
-	for _, resource := range resources.Items {
-		version, _, _ = unstructured.NestedString(resource.Object, []string{"status", "desired", "version"}...)
-
-	}
-	return version
+// GetK8sClient returns k8s client
+func (c *cluster) GetK8sClient() kubernetes.Interface {
+	return c.clientset
 }
 
-func (cluster *cluster) getNodeName() string {
-	nodes, err := cluster.clientset.CoreV1().Nodes().List(context.Background(), metav1.ListOptions{})
-	if err != nil {
-		return "k8s"
-	}
-	return nodes.Items[0].Name
+// GetMetadataClient returns the client of the objects metadata
+func (c *cluster) GetMetadataClient() metadata.Interface {
+	return c.metadataClient
 }
 
 // GetGVRs returns cluster GroupVersionResource to query kubernetes, receives
 // a boolean to determine if returns namespaced GVRs only or all GVRs, unless
-// resources is passed to filter
+// resources is passed to filter. Resources the cluster doesn't serve are skipped
 func (c *cluster) GetGVRs(namespaced bool, resources []string) ([]schema.GroupVersionResource, error) {
 	grvs := make([]schema.GroupVersionResource, 0)
 	if len(resources) == 0 {
@@ -365,20 +663,71 @@ func (c *cluster) GetGVRs(namespaced bool, resources []string) ([]schema.GroupVe
 			resources = append(resources, getClusterResources()...)
 		}
 	}
+	unavailable := make([]string, 0)
 	for _, resource := range resources {
 		gvr, err := c.GetGVR(resource)
+		if meta.IsNoMatchError(err) {
+			unavailable = append(unavailable, resource)
+			continue
+		}
 		if err != nil {
 			return nil, err
 		}
-
+		if containsGVR(grvs, gvr) {
+			continue
+		}
 		grvs = append(grvs, gvr)
 	}
+	if len(unavailable) > 0 {
+		slog.Warn("Skipping resources not served by the cluster", "resources", unavailable)
+	}
 
 	return grvs, nil
 }
 
+// GetGVR returns the preferred version of a resource, which can be qualified
+// with its group as kind.group or resource.group, e.g. ingresses.networking.k8s.io.
+// An unqualified resource served by a built-in group and by CRDs resolves to
+// the built-in group
 func (c *cluster) GetGVR(kind string) (schema.GroupVersionResource, error) {
-	return c.restMapper.ResourceFor(schema.GroupVersionResource{Resource: kind})
+	gr := schema.ParseGroupResource(strings.TrimSpace(kind))
+	gvr, err := c.resourceFor(gr)
+	if meta.IsNoMatchError(err) {
+		// the resource may have been installed since discovery was cached
+		if mapper, ok := c.restMapper.(meta.ResettableRESTMapper); ok {
+			mapper.Reset()
+			gvr, err = c.resourceFor(gr)
+		}
+	}
+	var ambiguous *meta.AmbiguousResourceError
+	if errors.As(err, &ambiguous) {
+		return schema.GroupVersionResource{}, fmt.Errorf("%w, qualify it with its group as resource.group", err)
+	}
+	return gvr, err
+}
+
+func (c *cluster) resourceFor(gr schema.GroupResource) (schema.GroupVersionResource, error) {
+	if gr.Group == "" {
+		gvrs, err := c.restMapper.ResourcesFor(gr.WithVersion(""))
+		if err != nil {
+			return schema.GroupVersionResource{}, err
+		}
+		for _, gvr := range gvrs {
+			if scheme.Scheme.IsGroupRegistered(gvr.Group) {
+				return gvr, nil
+			}
+		}
+	}
+	return c.restMapper.ResourceFor(gr.WithVersion(""))
+}
+
+func containsGVR(gvrs []schema.GroupVersionResource, gvr schema.GroupVersionResource) bool {
+	for _, g := range gvrs {
+		if g == gvr {
+			return true
+		}
+	}
+	return false
 }
 
 // IsClusterResource returns if a GVR is a cluster resource
@@ -438,48 +787,73 @@ func getNamespaceResources() []string {
 	}
 }
 
-func (c *cluster) CreateBomComponents(ctx context.Context, namespace string) ([]bom.Component, error) {
-	// collect addons info
-	var components []bom.Component
-	labels := map[string]string{
-		namespace: "component",
+type bomOptions struct {
+	catalog *ComponentCatalog
+}
+
+// BomOption configures the collection of a BOM
+type BomOption func(*bomOptions)
+
+// WithComponentCatalog sets the catalog detecting the BOM components, the
+// embedded DefaultComponentCatalog when nil
+func WithComponentCatalog(catalog *ComponentCatalog) BomOption {
+	return func(o *bomOptions) {
+		o.catalog = catalog
 	}
-	if namespace != "" && c.isOpenShift() {
-		labels = map[string]string{
-			"openshift-kube-apiserver":          "apiserver",
-			"openshift-kube-controller-manager": "kube-controller-manager",
-			"openshift-kube-scheduler":          "scheduler",
-			"openshift-etcd":                    "etcd",
-		}
+}
+
+func newBomOptions(opts []BomOption) *bomOptions {
+	o := &bomOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.catalog == nil {
+		o.catalog = DefaultComponentCatalog()
 	}
-	components, err := c.collectComponents(ctx, labels)
+	return o
+}
+
+func (c *cluster) CreateBomComponents(ctx context.Context, namespace string, opts ...BomOption) ([]bom.Component, error) {
+	// collect addons info
+	components, err := c.collectComponents(ctx, namespace, newBomOptions(opts).catalog)
 	if err != nil {
 		return nil, err
 	}
-	addonLabels := map[string]string{
-		namespace: "app.kubernetes.io/component=controller",
-	}
-	if namespace == "" || namespace == k8sComponentNamespace {
-		addonLabels[k8sComponentNamespace] = "k8s-app"
-	}
-	addons, err := c.collectComponents(ctx, addonLabels)
+	releases, err := c.collectHelmReleases(ctx, namespace)
 	if err != nil {
 		return nil, err
 	}
-	components = append(components, addons...)
+	components = append(components, releases...)
 	return components, nil
 }
 
-func (c *cluster) CreateClusterBom(ctx context.Context) (*bom.Result, error) {
-	components, err := c.CreateBomComponents(ctx, "")
+func (c *cluster) CreateClusterBom(ctx context.Context, opts ...BomOption) (*bom.Result, error) {
+	components, err := c.CreateBomComponents(ctx, "", opts...)
 	if err != nil {
 		return nil, err
 	}
+	var openShiftProperties map[string]string
+	if c.isOpenShift() {
+		var openShiftComponents []bom.Component
+		openShiftComponents, openShiftProperties, err = c.collectOpenShiftInventory(ctx)
+		if err != nil {
+			return nil, err
+		}
+		components = append(components, openShiftComponents...)
+	}
 	nodesInfo, err := c.CollectNodes(components)
 	if err != nil {
 		return nil, err
 	}
-	return c.getClusterBomInfo(components, nodesInfo)
+	br, err := c.getClusterBomInfo(ctx, components, nodesInfo)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range openShiftProperties {
+		br.Properties[k] = v
+	}
+	br.Dependencies = bom.BuildDependencies(br)
+	return br, nil
 }
 
 func extractDigest(imageId string) string {
@@ -517,13 +891,15 @@ func GetContainer(imageName, imageId string) (bom.Container, error) {
 	}
 
 	version := imageRef.Identifier()
-	return bom.Container{
+	container := bom.Container{
 		Repository: repoName,
 		Registry:   registryName,
 		ID:         fmt.Sprintf("%s:%s", repoName, version),
 		Digest:     imageDigest,
 		Version:    version,
-	}, nil
+	}
+	container.PURL = bom.ContainerPURL(container)
+	return container, nil
 }
 
 func (c *cluster) CollectNodes(components []bom.Component) ([]bom.NodeInfo, error) {
@@ -563,11 +939,15 @@ func NodeInfo(node corev1.Node) bom.NodeInfo {
 	if _, ok := node.Labels["node-role.kubernetes.io/master"]; ok {
 		nodeRole = "master"
 	}
+	kubeletVersion := trimString(k8sVersions(node.Status.NodeInfo.KubeletVersion), []string{"v", "V"})
 	return bom.NodeInfo{
 		NodeName:                node.Name,
-		KubeletVersion:          trimString(k8sVersions(node.Status.NodeInfo.KubeletVersion), []string{"v", "V"}),
+		KubeletVersion:          kubeletVersion,
 		ContainerRuntimeVersion: node.Status.NodeInfo.ContainerRuntimeVersion,
 		OsImage:                 node.Status.NodeInfo.OSImage,
+		KubeletPURL:             bom.ComponentPURL(kubernetesOrg, "kubelet", kubeletVersion),
+		ContainerRuntimePURL:    bom.ContainerRuntimePURL(node.Status.NodeInfo.ContainerRuntimeVersion),
+		OsPURL:                  bom.OSPURL(node.Status.NodeInfo.OSImage),
 		Properties: map[string]string{
 			"NodeRole":        nodeRole,
 			"HostName":        node.Name,
@@ -578,28 +958,31 @@ func NodeInfo(node corev1.Node) bom.NodeInfo {
 	}
 }
 
-func getPodsInfo(ctx context.Context, clientset *kubernetes.Clientset, labelSelector string, namespace string) (*corev1.PodList, error) {
-	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: labelSelector})
-	if err != nil {
-		return nil, err
-	}
-	return pods, nil
-}
-
-func (c *cluster) collectComponents(ctx context.Context, labels map[string]string) ([]bom.Component, error) {
+// collectComponents returns the components of the pods of the namespace, all
+// when empty, which the component catalog detects. The pods are listed by pages
+// so large clusters aren't loaded at once
+func (c *cluster) collectComponents(ctx context.Context, namespace string, catalog *ComponentCatalog) ([]bom.Component, error) {
 	components := make([]bom.Component, 0)
-	for namespace, labelSelector := range labels {
-		pods, err := getPodsInfo(ctx, c.clientset, labelSelector, namespace)
-		if err != nil {
-			continue
+	podPager := pager.New(pager.SimplePageFunc(func(opts metav1.ListOptions) (runtime.Object, error) {
+		return c.clientset.CoreV1().Pods(namespace).List(ctx, opts)
+	}))
+	podPager.PageSize = componentPodsPageSize
+	err := podPager.EachListItem(ctx, metav1.ListOptions{}, func(obj runtime.Object) error {
+		pod, ok := obj.(*corev1.Pod)
+		if !ok {
+			return nil
 		}
-		for _, pod := range pods.Items {
-			pi, err := PodInfo(pod, labelSelector)
-			if err != nil {
-				continue
-			}
+		if pi, err := PodComponent(*pod, catalog); err == nil {
 			components = append(components, *pi)
 		}
+		return nil
+	})
+	if err != nil {
+		if k8sapierror.IsNotFound(err) || k8sapierror.IsForbidden(err) {
+			slog.Warn("Unable to list component pods", "error", err)
+			return make([]bom.Component, 0), nil
+		}
+		return nil, err
 	}
 	return components, nil
 }
@@ -627,7 +1010,31 @@ func getImageIDsByStatuses(pod corev1.Pod) []string {
 	return ids
 }
 
+// PodInfo returns the component of a pod detected by the DefaultComponentCatalog,
+// named after the value of the labelSelector key when no rule matches the pod.
+//
+// Deprecated: use PodComponent, which detects the component with a catalog
 func PodInfo(pod corev1.Pod, labelSelector string) (*bom.Component, error) {
+	catalog := DefaultComponentCatalog()
+	if key, value, _ := strings.Cut(labelSelector, "="); key != "" {
+		if value == "" {
+			value = anyLabelValue
+		}
+		catalog.Components = append(catalog.Components, ComponentRule{
+			NameLabels: []string{"app.kubernetes.io/name", key},
+			Labels:     map[string]string{key: value},
+		})
+	}
+	return PodComponent(pod, catalog)
+}
+
+// PodComponent returns the component of a pod detected by the catalog, an error
+// when no rule of the catalog matches the pod
+func PodComponent(pod corev1.Pod, catalog *ComponentCatalog) (*bom.Component, error) {
+	m := catalog.match(pod)
+	if m == nil {
+		return nil, fmt.Errorf("no component rule matches pod %s/%s", pod.Namespace, pod.Name)
+	}
 	containers := make([]bom.Container, 0)
 
 	ids := getImageIDsByStatuses(pod)
@@ -644,24 +1051,31 @@ func PodInfo(pod corev1.Pod, labelSelector string) (*bom.Component, error) {
 
 	labels := pod.GetLabels()
 
-	name, version := labels["app.kubernetes.io/name"], labels["app.kubernetes.io/version"]
+	name, version := m.name, labels["app.kubernetes.io/version"]
 	props["Name"] = pod.Name
 	props["Type"] = labels["app.kubernetes.io/component"]
+	if props["Type"] == "" {
+		props["Type"] = m.rule.Type
+	}
+	if props["Type"] == "" {
+		props["Type"] = catalog.componentType(name)
+	}
 
-	if name == "" {
-		componentValue := labels[labelSelector]
-		name = upstreamRepoByName(componentValue)
-		if val, ok := CoreComponentPropertyType[name]; ok {
-			props["Type"] = val
-		}
+	orgName := m.rule.Org
+	if orgName == "" {
+		orgName = catalog.org(name)
 	}
-	orgName := upstreamOrgByName(name)
+	upstreamName := name
 	if len(orgName) > 0 {
 		name = fmt.Sprintf("%s/%s", orgName, name)
 	}
 
 	if version == "" {
-		version = trimString(findComponentVersion(containers, labels[labelSelector]), []string{"v", "V"})
+		version = m.rule.containerVersion(containers)
+		if version == "" {
+			version = findComponentVersion(containers, m.versionHint)
+		}
+		version = trimString(version, []string{"v", "V"})
 	}
 
 	return &bom.Component{
@@ -670,6 +1084,8 @@ func PodInfo(pod corev1.Pod, labelSelector string) (*bom.Component, error) {
 		Version:    version,
 		Properties: props,
 		Containers: containers,
+		PURL:       bom.ComponentPURL(orgName, upstreamName, version),
+		NodeName:   pod.Spec.NodeName,
 	}, nil
 }
 
@@ -705,7 +1121,7 @@ func (c *cluster) isOpenShift() bool {
 	return !k8sapierror.IsNotFound(err)
 }
 
-func (c *cluster) getClusterBomInfo(components []bom.Component, nodeInfo []bom.NodeInfo) (*bom.Result, error) {
+func (c *cluster) getClusterBomInfo(ctx context.Context, components []bom.Component, nodeInfo []bom.NodeInfo) (*bom.Result, error) {
 	name, version, err := c.ClusterNameVersion()
 	if err != nil {
 		return nil, err
@@ -718,80 +1134,73 @@ func (c *cluster) getClusterBomInfo(components []bom.Component, nodeInfo []bom.N
 		Properties: map[string]string{"Name": name, "Type": "cluster"},
 		NodesInfo:  nodeInfo,
 	}
+	if id, err := c.GetClusterID(ctx); err != nil {
+		slog.Warn("Unable to identify cluster", "error", err)
+	} else {
+		br.Properties["ClusterID"] = id
+	}
 	return br, nil
 }
 
 func (c *cluster) ClusterNameVersion() (string, string, error) {
-	rawCfg, err := c.cConfig.RawConfig()
-	if err != nil {
-		return "", "", err
-	}
-	clusterName := "k8s.io/kubernetes"
-	if len(rawCfg.Contexts) > 0 {
-		if c.currentContext != "" {
-			rawCfg.CurrentContext = c.currentContext
-		}
-		if clusterContext, ok := rawCfg.Contexts[rawCfg.CurrentContext]; ok {
-			clusterName = clusterContext.Cluster
-		}
-	}
-	version, err := c.clientset.ServerVersion()
+	version, err := c.clientset.Discovery().ServerVersion()
 	if err != nil {
 		return "", "", err
 	}
-	return clusterName, version.GitVersion, nil
+	return c.clusterName, version.GitVersion, nil
 }
 
-// ListImagePullSecretsByPodSpec return image pull secrets by pod spec
-func (r *cluster) ListImagePullSecretsByPodSpec(ctx context.Context, spec *corev1.PodSpec, ns string) (map[string]docker.Auth, error) {
+// ListImagePullSecretsByPodSpec return image pull secrets by pod spec, the service
+// accounts and secrets of the namespace are listed through the cache of a scan, or
+// only the referenced ones are read when the cache is nil
+func (r *cluster) ListImagePullSecretsByPodSpec(ctx context.Context, spec *corev1.PodSpec, ns string, cache *CredentialCache) (map[string]docker.Auth, error) {
 	if spec == nil {
 		return map[string]docker.Auth{}, nil
 	}
-	imagePullSecrets := spec.ImagePullSecrets
-
-	sa, err := r.getServiceAccountByPodSpec(ctx, spec, ns)
-	if err != nil && !k8sapierror.IsNotFound(err) && !k8sapierror.IsForbidden(err) {
-		return nil, err
-	}
-	imagePullSecrets = append(sa.ImagePullSecrets, imagePullSecrets...)
-
-	secrets, err := r.ListByLocalObjectReferences(ctx, imagePullSecrets, ns)
-	if err != nil {
-		return nil, err
+	var creds *namespaceCredentials
+	if cache == nil {
+		creds = &namespaceCredentials{
+			loaded:          true,
+			serviceAccounts: make(map[string]*corev1.ServiceAccount),
+			secrets:         make(map[string]*corev1.Secret),
+		}
+	} else {
+		creds = cache.namespace(ns)
+		creds.mu.Lock()
+		defer creds.mu.Unlock()
+		if err := r.loadNamespaceCredentials(ctx, ns, creds); err != nil {
+			return nil, err
+		}
 	}
 
-	return mapDockerRegistryServersToAuths(secrets, true)
-}
-
-func (r *cluster) getServiceAccountByPodSpec(ctx context.Context, spec *corev1.PodSpec, ns string) (*corev1.ServiceAccount, error) {
 	serviceAccountName := spec.ServiceAccountName
 	if serviceAccountName == "" {
 		serviceAccountName = serviceAccountDefault
 	}
-	sa, err := r.clientset.CoreV1().ServiceAccounts(ns).Get(ctx, serviceAccountName, metav1.GetOptions{})
+	sa, err := r.serviceAccount(ctx, creds, ns, serviceAccountName)
 	if err != nil {
-		return sa, fmt.Errorf("getting service account by name: %s/%s: %w", ns, serviceAccountName, err)
+		return nil, err
+	}
+	imagePullSecrets := spec.ImagePullSecrets
+	if sa != nil {
+		imagePullSecrets = append(sa.ImagePullSecrets, imagePullSecrets...)
 	}
-	return sa, nil
-}
 
-func (r *cluster) ListByLocalObjectReferences(ctx context.Context, refs []corev1.LocalObjectReference, ns string) ([]*corev1.Secret, error) {
 	secrets := make([]*corev1.Secret, 0)
-
-	for _, secretRef := range refs {
+	for _, secretRef := range imagePullSecrets {
 		if secretRef.Name == "" {
 			continue
 		}
-		secret, err := r.clientset.CoreV1().Secrets(ns).Get(ctx, secretRef.Name, metav1.GetOptions{})
+		secret, err := r.secret(ctx, creds, ns, secretRef.Name)
 		if err != nil {
-			if k8sapierror.IsNotFound(err) || k8sapierror.IsForbidden(err) {
-				continue
-			}
-			return nil, fmt.Errorf("getting secret by name: %s/%s: %w", ns, secretRef.Name, err)
+			return nil, err
+		}
+		if secret != nil {
+			secrets = append(secrets, secret)
 		}
-		secrets = append(secrets, secret)
 	}
-	return secrets, nil
+
+	return mapDockerRegistryServersToAuths(secrets, true)
 }
 
 // MapDockerRegistryServersToAuths creates the mapping from a Docker registry server
@@ -926,38 +1335,39 @@ func mapToPodSpec(objectMap map[string]interface{}) (*corev1.PodSpec, error) {
 	return ps, nil
 }
 
-func (r *cluster) AuthByResource(resource unstructured.Unstructured) (map[string]docker.Auth, error) {
+type authOptions struct {
+	cache *CredentialCache
+}
+
+// AuthOption configures AuthByResource
+type AuthOption func(*authOptions)
+
+// WithCredentialCache reads the credentials through the cache of a scan, which lists
+// them once per namespace. Without it, the referenced credentials are read for
+// every resource
+func WithCredentialCache(cache *CredentialCache) AuthOption {
+	return func(o *authOptions) {
+		o.cache = cache
+	}
+}
+
+func (r *cluster) AuthByResource(resource unstructured.Unstructured, opts ...AuthOption) (map[string]docker.Auth, error) {
+	o := &authOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
 	podSpec, err := getWorkloadPodSpec(resource)
 	if err != nil {
 		return nil, err
 	}
 	var serverAuths map[string]docker.Auth
-	serverAuths, err = r.ListImagePullSecretsByPodSpec(context.Background(), podSpec, resource.GetNamespace())
+	serverAuths, err = r.ListImagePullSecretsByPodSpec(context.Background(), podSpec, resource.GetNamespace(), o.cache)
 	if err != nil {
 		return nil, err
 	}
 	return serverAuths, nil
 }
 
-func upstreamOrgByName(component string) string {
-	for key, components := range UpstreamOrgName {
-		for _, c := range strings.Split(components, ",") {
-			if strings.TrimSpace(c) == strings.ToLower(component) {
-				return key
-			}
-		}
-	}
-	return ""
-}
-
-func upstreamRepoByName(component string) string {
-	if val, ok := UpstreamRepoName[component]; ok {
-		return val
-	}
-
-	return component
-}
-
 func trimString(version string, trimValues []string) string {
 	for _, v := range trimValues {
 		version = strings.Trim(version, v)
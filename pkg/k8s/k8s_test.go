@@ -1,14 +1,28 @@
 package k8s
 
 import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/aquasecurity/trivy-kubernetes/pkg/bom"
+	"github.com/aquasecurity/trivy-kubernetes/pkg/k8s/docker"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 )
@@ -59,6 +73,16 @@ func TestGetGVR(t *testing.T) {
 			GroupVersionKind: schema.GroupVersionKind{Group: "testapi", Version: "test", Kind: "MyObject"},
 			ExpectedResource: schema.GroupVersionResource{Resource: "myobjects", Group: "testapi", Version: "test"},
 		},
+		{
+			Resource:         "myobjects.testapi",
+			GroupVersionKind: schema.GroupVersionKind{Group: "testapi", Version: "test", Kind: "MyObject"},
+			ExpectedResource: schema.GroupVersionResource{Resource: "myobjects", Group: "testapi", Version: "test"},
+		},
+		{
+			Resource:         "myobjects.otherapi",
+			GroupVersionKind: schema.GroupVersionKind{Group: "testapi", Version: "test", Kind: "MyObject"},
+			Err:              true,
+		},
 	}
 
 	for _, test := range tests {
@@ -134,6 +158,7 @@ func TestPodInfo(t *testing.T) {
 					Labels:    map[string]string{"component": "kube-apiserver"},
 				},
 				Spec: corev1.PodSpec{
+					NodeName:   "node1",
 					Containers: []corev1.Container{{Image: "k8s.gcr.io/kube-apiserver:v1.21.1"}},
 				},
 				Status: corev1.PodStatus{
@@ -148,6 +173,8 @@ func TestPodInfo(t *testing.T) {
 				Namespace: "kube-system",
 				Name:      "k8s.io/apiserver",
 				Version:   "1.21.1",
+				PURL:      "pkg:k8s/k8s.io/apiserver@1.21.1",
+				NodeName:  "node1",
 				Properties: map[string]string{
 					"Name": "pod1",
 					"Type": "controlPlane",
@@ -159,6 +186,7 @@ func TestPodInfo(t *testing.T) {
 						Repository: "kube-apiserver",
 						Registry:   "k8s.gcr.io",
 						Digest:     "18e61c783b41758dd391ab901366ec3546b26fae00eef7e223d1f94da808e02f",
+						PURL:       "pkg:oci/kube-apiserver@sha256%3A18e61c783b41758dd391ab901366ec3546b26fae00eef7e223d1f94da808e02f?repository_url=k8s.gcr.io%2Fkube-apiserver&tag=v1.21.1",
 					},
 				},
 			},
@@ -187,6 +215,7 @@ func TestPodInfo(t *testing.T) {
 				Namespace: "kube-system",
 				Name:      "go.etcd.io/etcd/v3",
 				Version:   "3.5.15-0",
+				PURL:      "pkg:golang/go.etcd.io/etcd/v3@v3.5.15-0",
 				Properties: map[string]string{
 					"Name": "etcd-minikube",
 					"Type": "controlPlane",
@@ -198,6 +227,7 @@ func TestPodInfo(t *testing.T) {
 						Repository: "etcd",
 						Registry:   "registry.k8s.io",
 						Digest:     "a6dc63e6e8cfa0307d7851762fa6b629afb18f28d8aa3fab5a6e91b4af60026a",
+						PURL:       "pkg:oci/etcd@sha256%3Aa6dc63e6e8cfa0307d7851762fa6b629afb18f28d8aa3fab5a6e91b4af60026a?repository_url=registry.k8s.io%2Fetcd&tag=3.5.15-0",
 					},
 				},
 			},
@@ -232,6 +262,7 @@ func TestPodInfo(t *testing.T) {
 				Namespace: "ingress-nginx",
 				Name:      "k8s.io/ingress-nginx",
 				Version:   "1.11.0",
+				PURL:      "pkg:k8s/k8s.io/ingress-nginx@1.11.0",
 				Properties: map[string]string{
 					"Name": "ingress-nginx-controller-8547bfc86c-dr7lq",
 					"Type": "controller",
@@ -243,6 +274,7 @@ func TestPodInfo(t *testing.T) {
 						Repository: "ingress-nginx/controller",
 						Registry:   "registry.k8s.io",
 						Digest:     "a886e56d532d1388c77c8340261149d974370edca1093af4c97a96fb1467cb39",
+						PURL:       "pkg:oci/controller@sha256%3Aa886e56d532d1388c77c8340261149d974370edca1093af4c97a96fb1467cb39?repository_url=registry.k8s.io%2Fingress-nginx%2Fcontroller&tag=v1.11.0",
 					},
 				},
 			},
@@ -286,6 +318,7 @@ func TestPodInfo(t *testing.T) {
 				Namespace: "ingress-nginx",
 				Name:      "k8s.io/ingress-nginx",
 				Version:   "1.11.0",
+				PURL:      "pkg:k8s/k8s.io/ingress-nginx@1.11.0",
 				Properties: map[string]string{
 					"Name": "ingress-nginx-controller-8547bfc86c-dr7lq",
 					"Type": "controller",
@@ -297,6 +330,7 @@ func TestPodInfo(t *testing.T) {
 						Repository: "ingress-nginx/controller",
 						Registry:   "registry.k8s.io",
 						Digest:     "a886e56d532d1388c77c8340261149d974370edca1093af4c97a96fb1467cb39",
+						PURL:       "pkg:oci/controller@sha256%3Aa886e56d532d1388c77c8340261149d974370edca1093af4c97a96fb1467cb39?repository_url=registry.k8s.io%2Fingress-nginx%2Fcontroller&tag=v1.11.0",
 					},
 					{
 						ID:         "ingress-nginx/controller:v1.21.0",
@@ -304,6 +338,7 @@ func TestPodInfo(t *testing.T) {
 						Repository: "ingress-nginx/controller",
 						Registry:   "registry.k8s.io",
 						Digest:     "a886e56d532d1388c77c8340261149d974370edca1093af4c97a96fb1467cb51",
+						PURL:       "pkg:oci/controller@sha256%3Aa886e56d532d1388c77c8340261149d974370edca1093af4c97a96fb1467cb51?repository_url=registry.k8s.io%2Fingress-nginx%2Fcontroller&tag=v1.21.0",
 					},
 				},
 			},
@@ -347,6 +382,7 @@ func TestPodInfo(t *testing.T) {
 				Namespace: "ingress-nginx",
 				Name:      "k8s.io/ingress-nginx",
 				Version:   "1.11.0",
+				PURL:      "pkg:k8s/k8s.io/ingress-nginx@1.11.0",
 				Properties: map[string]string{
 					"Name": "ingress-nginx-controller-8547bfc86c-dr7lq",
 					"Type": "controller",
@@ -358,6 +394,7 @@ func TestPodInfo(t *testing.T) {
 						Repository: "ingress-nginx/controller",
 						Registry:   "registry.k8s.io",
 						Digest:     "a886e56d532d1388c77c8340261149d974370edca1093af4c97a96fb1467cb39",
+						PURL:       "pkg:oci/controller@sha256%3Aa886e56d532d1388c77c8340261149d974370edca1093af4c97a96fb1467cb39?repository_url=registry.k8s.io%2Fingress-nginx%2Fcontroller&tag=v1.11.0",
 					},
 					{
 						ID:         "ingress-nginx/controller:v1.21.0",
@@ -365,6 +402,7 @@ func TestPodInfo(t *testing.T) {
 						Repository: "ingress-nginx/controller",
 						Registry:   "registry.k8s.io",
 						Digest:     "a886e56d532d1388c77c8340261149d974370edca1093af4c97a96fb1467cb51",
+						PURL:       "pkg:oci/controller@sha256%3Aa886e56d532d1388c77c8340261149d974370edca1093af4c97a96fb1467cb51?repository_url=registry.k8s.io%2Fingress-nginx%2Fcontroller&tag=v1.21.0",
 					},
 				},
 			},
@@ -409,6 +447,7 @@ func TestPodInfo(t *testing.T) {
 				Namespace: "ingress-nginx",
 				Name:      "k8s.io/ingress-nginx",
 				Version:   "1.11.0",
+				PURL:      "pkg:k8s/k8s.io/ingress-nginx@1.11.0",
 				Properties: map[string]string{
 					"Name": "ingress-nginx-controller-8547bfc86c-dr7lq",
 					"Type": "controller",
@@ -420,6 +459,7 @@ func TestPodInfo(t *testing.T) {
 						Repository: "ingress-nginx/controller",
 						Registry:   "registry.k8s.io",
 						Digest:     "a886e56d532d1388c77c8340261149d974370edca1093af4c97a96fb1467cb39",
+						PURL:       "pkg:oci/controller@sha256%3Aa886e56d532d1388c77c8340261149d974370edca1093af4c97a96fb1467cb39?repository_url=registry.k8s.io%2Fingress-nginx%2Fcontroller&tag=v1.11.0",
 					},
 				},
 			},
@@ -475,6 +515,9 @@ func TestNodeInfo(t *testing.T) {
 					"OperatingSystem": "linux",
 					"Architecture":    "amd64",
 				},
+				KubeletPURL:          "pkg:k8s/k8s.io/kubelet@1.21.1",
+				ContainerRuntimePURL: "pkg:golang/github.com/containerd/containerd@v1.5.2",
+				OsPURL:               "pkg:generic/ubuntu@21.04",
 			},
 		},
 	}
@@ -545,6 +588,7 @@ func TestGetContainer(t *testing.T) {
 				Repository: "project/image",
 				Registry:   "gcr.io",
 				Digest:     "1420cefd4b20014b3361951c22593de6e9a2476bbbadd1759464eab5bfc0d34f",
+				PURL:       "pkg:oci/image@sha256%3A1420cefd4b20014b3361951c22593de6e9a2476bbbadd1759464eab5bfc0d34f?repository_url=gcr.io%2Fproject%2Fimage&tag=v1.0.0",
 			},
 		},
 		{
@@ -557,6 +601,7 @@ func TestGetContainer(t *testing.T) {
 				Repository: "project/image",
 				Registry:   "gcr.io",
 				Digest:     "1420cefd4b20014b3361951c22593de6e9a2476bbbadd1759464eab5bfc0d34f",
+				PURL:       "pkg:oci/image@sha256%3A1420cefd4b20014b3361951c22593de6e9a2476bbbadd1759464eab5bfc0d34f?repository_url=gcr.io%2Fproject%2Fimage&tag=v1.0.0",
 			},
 		},
 		{
@@ -569,6 +614,7 @@ func TestGetContainer(t *testing.T) {
 				Repository: "project/image",
 				Registry:   "myregistry.com:5000",
 				Digest:     "1420cefd4b20014b3361951c22593de6e9a2476bbbadd1759464eab5bfc0d34f",
+				PURL:       "pkg:oci/image@sha256%3A1420cefd4b20014b3361951c22593de6e9a2476bbbadd1759464eab5bfc0d34f?repository_url=myregistry.com%3A5000%2Fproject%2Fimage&tag=v1.0.0",
 			},
 		},
 		{
@@ -581,6 +627,7 @@ func TestGetContainer(t *testing.T) {
 				Repository: "project/image",
 				Registry:   "gcr.io",
 				Digest:     "1420cefd4b20014b3361951c22593de6e9a2476bbbadd1759464eab5bfc0d34f",
+				PURL:       "pkg:oci/image@sha256%3A1420cefd4b20014b3361951c22593de6e9a2476bbbadd1759464eab5bfc0d34f?repository_url=gcr.io%2Fproject%2Fimage&tag=v1.0.0",
 			},
 		},
 		{
@@ -593,6 +640,7 @@ func TestGetContainer(t *testing.T) {
 				Repository: "project/image",
 				Registry:   "index.docker.io",
 				Digest:     "1420cefd4b20014b3361951c22593de6e9a2476bbbadd1759464eab5bfc0d34f",
+				PURL:       "pkg:oci/image@sha256%3A1420cefd4b20014b3361951c22593de6e9a2476bbbadd1759464eab5bfc0d34f?repository_url=index.docker.io%2Fproject%2Fimage&tag=v1.0.0",
 			},
 		},
 		{
@@ -605,6 +653,7 @@ func TestGetContainer(t *testing.T) {
 				Repository: "alpine",
 				Registry:   "index.docker.io",
 				Digest:     "d6d0a0eb4d40ef96f2310ead734848b9c819bb97c9d846385c4aca1767186cd4",
+				PURL:       "pkg:oci/alpine@sha256%3Ad6d0a0eb4d40ef96f2310ead734848b9c819bb97c9d846385c4aca1767186cd4?repository_url=index.docker.io%2Falpine&tag=latest",
 			},
 		},
 		{
@@ -617,6 +666,7 @@ func TestGetContainer(t *testing.T) {
 				Repository: "coredns/coredns",
 				Registry:   "registry.k8s.io",
 				Digest:     "1eeb4c7316bacb1d4c8ead65571cd92dd21e27359f0d4917f1a5822a73b75db1",
+				PURL:       "pkg:oci/coredns@sha256%3A1eeb4c7316bacb1d4c8ead65571cd92dd21e27359f0d4917f1a5822a73b75db1?repository_url=registry.k8s.io%2Fcoredns%2Fcoredns&tag=v1.11.1",
 			},
 		},
 		{
@@ -629,6 +679,7 @@ func TestGetContainer(t *testing.T) {
 				Repository: "alpine",
 				Registry:   "index.docker.io",
 				Digest:     "a8560b36e8b8210634f77d9f7f9efd7ffa463e380b75e2e74aff4511df3ef88c",
+				PURL:       "pkg:oci/alpine@sha256%3Aa8560b36e8b8210634f77d9f7f9efd7ffa463e380b75e2e74aff4511df3ef88c?repository_url=index.docker.io%2Falpine&tag=latest",
 			},
 		},
 		{
@@ -641,6 +692,7 @@ func TestGetContainer(t *testing.T) {
 				Repository: "alpine",
 				Registry:   "index.docker.io",
 				Digest:     "a8560b36e8b8210634f77d9f7f9efd7ffa463e380b75e2e74aff4511df3ef88c",
+				PURL:       "pkg:oci/alpine@sha256%3Aa8560b36e8b8210634f77d9f7f9efd7ffa463e380b75e2e74aff4511df3ef88c?repository_url=index.docker.io%2Falpine&tag=latest",
 			},
 		},
 		{
@@ -653,6 +705,7 @@ func TestGetContainer(t *testing.T) {
 				Repository: "ingress-nginx/controller",
 				Registry:   "registry.k8s.io",
 				Digest:     "a886e56d532d1388c77c8340261149d974370edca1093af4c97a96fb1467cb39",
+				PURL:       "pkg:oci/controller@sha256%3Aa886e56d532d1388c77c8340261149d974370edca1093af4c97a96fb1467cb39?repository_url=registry.k8s.io%2Fingress-nginx%2Fcontroller&tag=v1.11.0",
 			},
 		},
 		{
@@ -665,6 +718,7 @@ func TestGetContainer(t *testing.T) {
 				Repository: "ingress-nginx/controller",
 				Registry:   "registry.k8s.io",
 				Digest:     "a886e56d532d1388c77c8340261149d974370edca1093af4c97a96fb1467cb39",
+				PURL:       "pkg:oci/controller@sha256%3Aa886e56d532d1388c77c8340261149d974370edca1093af4c97a96fb1467cb39?repository_url=registry.k8s.io%2Fingress-nginx%2Fcontroller&tag=v1.11.0",
 			},
 		},
 		{
@@ -677,6 +731,7 @@ func TestGetContainer(t *testing.T) {
 				Repository: "repository/my-repo",
 				Registry:   "12131415.dkr.ecr.us-west-2.amazonaws.com",
 				Digest:     "569cfa0ff435f3a076a1b06a1f45d772ee3f5d4fbf6b39242a573c0cff632d69",
+				PURL:       "pkg:oci/my-repo@sha256%3A569cfa0ff435f3a076a1b06a1f45d772ee3f5d4fbf6b39242a573c0cff632d69?repository_url=12131415.dkr.ecr.us-west-2.amazonaws.com%2Frepository%2Fmy-repo&tag=v1.0.0",
 			},
 		},
 		{
@@ -708,3 +763,334 @@ func TestGetContainer(t *testing.T) {
 		})
 	}
 }
+
+func TestNewCluster(t *testing.T) {
+	clientset := fake.NewClientset(
+		&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "node-1",
+				Labels: map[string]string{"node-role.kubernetes.io/control-plane": ""},
+			},
+			Spec: corev1.NodeSpec{ProviderID: "kind://docker/kind/node-1"},
+			Status: corev1.NodeStatus{NodeInfo: corev1.NodeSystemInfo{
+				KubeletVersion:          "v1.30.0",
+				ContainerRuntimeVersion: "containerd://1.7.15",
+				OSImage:                 "Debian GNU/Linux 12 (bookworm)",
+			}},
+		},
+		&corev1.ServiceAccount{
+			ObjectMeta:       metav1.ObjectMeta{Name: "default", Namespace: "app"},
+			ImagePullSecrets: []corev1.LocalObjectReference{{Name: "registry"}},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "app"},
+			Type:       corev1.SecretTypeDockerConfigJson,
+			Data: map[string][]byte{
+				corev1.DockerConfigJsonKey: []byte(`{"auths":{"ghcr.io":{"username":"user","password":"pass"}}}`),
+			},
+		},
+	)
+	clientset.Discovery().(*fakediscovery.FakeDiscovery).FakedServerVersion = &version.Info{GitVersion: "v1.30.0"}
+
+	c, err := NewCluster(clientset, nil, meta.NewDefaultRESTMapper(nil))
+	require.NoError(t, err)
+	assert.Equal(t, "1.30.0", c.GetClusterVersion())
+	assert.Equal(t, "default", c.GetCurrentNamespace())
+	assert.Nil(t, c.GetK8sClientSet())
+	assert.Equal(t, "kind", c.Platform().Name)
+
+	nodes, err := c.(*cluster).CollectNodes(nil)
+	require.NoError(t, err)
+	require.Len(t, nodes, 1)
+	assert.Equal(t, "node-1", nodes[0].NodeName)
+	assert.Equal(t, "master", nodes[0].Properties["NodeRole"])
+
+	auths, err := c.AuthByResource(unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata":   map[string]interface{}{"name": "api", "namespace": "app"},
+		"spec": map[string]interface{}{
+			"containers": []interface{}{map[string]interface{}{"name": "api", "image": "ghcr.io/acme/api:1.0"}},
+		},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]docker.Auth{"ghcr.io": {Username: "user", Password: "pass"}}, auths)
+
+	name, gitVersion, err := c.(*cluster).ClusterNameVersion()
+	require.NoError(t, err)
+	assert.Equal(t, "k8s.io/kubernetes", name)
+	assert.Equal(t, "v1.30.0", gitVersion)
+}
+
+func TestGetClusterFromRESTConfig(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/version" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"gitVersion":"v1.31.2"}`))
+	}))
+	defer server.Close()
+
+	namespaceFile := filepath.Join(t.TempDir(), "namespace")
+	require.NoError(t, os.WriteFile(namespaceFile, []byte("trivy-system\n"), 0o600))
+	defaultNamespaceFile := serviceAccountNamespaceFile
+	serviceAccountNamespaceFile = namespaceFile
+	defer func() { serviceAccountNamespaceFile = defaultNamespaceFile }()
+
+	c, err := GetClusterFromRESTConfig(&rest.Config{Host: server.URL}, WithQPS(50), WithBurst(100))
+	require.NoError(t, err)
+	assert.Equal(t, "1.31.2", c.GetClusterVersion())
+	assert.Equal(t, "trivy-system", c.GetCurrentNamespace())
+
+	name, gitVersion, err := c.(*cluster).ClusterNameVersion()
+	require.NoError(t, err)
+	assert.Equal(t, defaultClusterName, name, "the api server host is not a cluster name")
+	assert.Equal(t, "v1.31.2", gitVersion)
+
+	c, err = GetClusterFromRESTConfig(&rest.Config{Host: server.URL}, WithClusterName("prod-eu"))
+	require.NoError(t, err)
+	name, _, err = c.(*cluster).ClusterNameVersion()
+	require.NoError(t, err)
+	assert.Equal(t, "prod-eu", name)
+}
+
+func TestGetKubeConfigContexts(t *testing.T) {
+	config := clientcmdapi.NewConfig()
+	config.Clusters["prod-cluster"] = &clientcmdapi.Cluster{Server: "https://prod.example.com"}
+	config.Clusters["dev-cluster"] = &clientcmdapi.Cluster{Server: "https://dev.example.com"}
+	config.AuthInfos["user"] = &clientcmdapi.AuthInfo{Token: "token"}
+	config.Contexts["prod"] = &clientcmdapi.Context{Cluster: "prod-cluster", AuthInfo: "user", Namespace: "apps"}
+	config.Contexts["dev"] = &clientcmdapi.Context{Cluster: "dev-cluster", AuthInfo: "user"}
+	config.CurrentContext = "dev"
+	kubeConfig := filepath.Join(t.TempDir(), "config")
+	require.NoError(t, clientcmd.WriteToFile(*config, kubeConfig))
+
+	contexts, err := GetKubeConfigContexts(WithKubeConfig(kubeConfig))
+	require.NoError(t, err)
+	assert.Equal(t, []KubeConfigContext{
+		{Name: "dev", Cluster: "dev-cluster"},
+		{Name: "prod", Cluster: "prod-cluster", Namespace: "apps"},
+	}, contexts)
+}
+
+func TestGetClusterID(t *testing.T) {
+	tests := []struct {
+		name    string
+		objects []runtime.Object
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "kube-system namespace",
+			objects: []runtime.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "kube-system-uid"}},
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default", UID: "default-uid"}},
+			},
+			want: "kube-system:kube-system-uid",
+		},
+		{
+			name: "default namespace fallback",
+			objects: []runtime.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default", UID: "default-uid"}},
+			},
+			want: "default:default-uid",
+		},
+		{
+			name: "kubernetes service fallback",
+			objects: []runtime.Object{
+				&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "kubernetes", Namespace: "default", UID: "service-uid"}},
+			},
+			want: "service:service-uid",
+		},
+		{
+			name:    "unidentified cluster",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clientset := fake.NewClientset(test.objects...)
+			clientset.Discovery().(*fakediscovery.FakeDiscovery).FakedServerVersion = &version.Info{GitVersion: "v1.30.0"}
+			c, err := NewCluster(clientset, nil, meta.NewDefaultRESTMapper(nil))
+			require.NoError(t, err)
+
+			id, err := c.GetClusterID(context.Background())
+			if test.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.want, id)
+
+			b, err := c.(*cluster).getClusterBomInfo(context.Background(), nil, nil)
+			require.NoError(t, err)
+			assert.Equal(t, test.want, b.Properties["ClusterID"])
+		})
+	}
+}
+
+func TestGetGVRs(t *testing.T) {
+	clientset := fake.NewClientset()
+	discovery := clientset.Discovery().(*fakediscovery.FakeDiscovery)
+	discovery.FakedServerVersion = &version.Info{GitVersion: "v1.30.0"}
+	discovery.Resources = []*metav1.APIResourceList{
+		{
+			GroupVersion: "networking.k8s.io/v1",
+			APIResources: []metav1.APIResource{
+				{Name: "ingresses", SingularName: "ingress", Kind: "Ingress", Namespaced: true},
+				{Name: "networkpolicies", SingularName: "networkpolicy", Kind: "NetworkPolicy", Namespaced: true},
+			},
+		},
+		{
+			GroupVersion: "extensions.example.com/v1",
+			APIResources: []metav1.APIResource{
+				{Name: "ingresses", SingularName: "ingress", Kind: "Ingress", Namespaced: true},
+			},
+		},
+	}
+	c, err := NewCluster(clientset, nil, nil)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		resources []string
+		want      []schema.GroupVersionResource
+	}{
+		{
+			name:      "unavailable resources are skipped",
+			resources: []string{"networkpolicies", "mycrds"},
+			want:      []schema.GroupVersionResource{{Group: "networking.k8s.io", Version: "v1", Resource: "networkpolicies"}},
+		},
+		{
+			name:      "unqualified resource served by several groups",
+			resources: []string{"ingresses"},
+			want:      []schema.GroupVersionResource{{Group: "networking.k8s.io", Version: "v1", Resource: "ingresses"}},
+		},
+		{
+			name:      "qualified resource",
+			resources: []string{"ingresses.extensions.example.com"},
+			want:      []schema.GroupVersionResource{{Group: "extensions.example.com", Version: "v1", Resource: "ingresses"}},
+		},
+		{
+			name:      "qualified kind",
+			resources: []string{"Ingress.networking.k8s.io", "ingresses.networking.k8s.io"},
+			want:      []schema.GroupVersionResource{{Group: "networking.k8s.io", Version: "v1", Resource: "ingresses"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gvrs, err := c.GetGVRs(true, test.resources)
+			require.NoError(t, err)
+			assert.Equal(t, test.want, gvrs)
+		})
+	}
+
+	t.Run("resources installed after discovery", func(t *testing.T) {
+		discovery.Resources = append(discovery.Resources, &metav1.APIResourceList{
+			GroupVersion: "example.com/v1",
+			APIResources: []metav1.APIResource{{Name: "mycrds", SingularName: "mycrd", Kind: "MyCRD", Namespaced: true}},
+		})
+		gvr, err := c.GetGVR("mycrds")
+		require.NoError(t, err)
+		assert.Equal(t, schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "mycrds"}, gvr)
+	})
+}
+
+func TestCredentialOptions(t *testing.T) {
+	var headers http.Header
+	// credentials are only sent over TLS
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header.Clone()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"gitVersion":"v1.31.2"}`))
+	}))
+	defer server.Close()
+	caFile := filepath.Join(t.TempDir(), "ca.crt")
+	require.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0o600))
+
+	config := clientcmdapi.NewConfig()
+	config.Clusters["unreachable"] = &clientcmdapi.Cluster{Server: "https://unreachable.example.com"}
+	config.AuthInfos["user"] = &clientcmdapi.AuthInfo{Token: "kubeconfig-token"}
+	config.Contexts["default"] = &clientcmdapi.Context{Cluster: "unreachable", AuthInfo: "user"}
+	config.CurrentContext = "default"
+	kubeConfig := filepath.Join(t.TempDir(), "config")
+	require.NoError(t, clientcmd.WriteToFile(*config, kubeConfig))
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("file-token"), 0o600))
+
+	tests := []struct {
+		name        string
+		opts        []ClusterOption
+		wantHeaders map[string][]string
+		wantErr     string
+	}{
+		{
+			name: "token and impersonation",
+			opts: []ClusterOption{
+				WithToken("ci-token"),
+				WithImpersonate("scanner", "readers", "auditors"),
+				WithImpersonateUID("1234"),
+			},
+			wantHeaders: map[string][]string{
+				"Authorization":     {"Bearer ci-token"},
+				"Impersonate-User":  {"scanner"},
+				"Impersonate-Group": {"readers", "auditors"},
+				"Impersonate-Uid":   {"1234"},
+			},
+		},
+		{
+			name: "token file",
+			opts: []ClusterOption{WithTokenFile(tokenFile)},
+			wantHeaders: map[string][]string{
+				"Authorization": {"Bearer file-token"},
+			},
+		},
+		{
+			name:    "token and token file",
+			opts:    []ClusterOption{WithToken("ci-token"), WithTokenFile(tokenFile)},
+			wantErr: "mutually exclusive",
+		},
+		{
+			name:    "certificate without key",
+			opts:    []ClusterOption{WithClientCertificate("client.crt", "")},
+			wantErr: "must be set together",
+		},
+		{
+			name:    "token and certificate",
+			opts:    []ClusterOption{WithToken("ci-token"), WithClientCertificate("client.crt", "client.key")},
+			wantErr: "mutually exclusive",
+		},
+		{
+			name:    "impersonated group without user",
+			opts:    []ClusterOption{WithImpersonate("", "readers")},
+			wantErr: "requires impersonating a user",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			headers = nil
+			opts := append([]ClusterOption{WithKubeConfig(kubeConfig), WithServer(server.URL), WithCAFile(caFile)}, test.opts...)
+			_, err := GetCluster(opts...)
+			if test.wantErr != "" {
+				require.ErrorContains(t, err, test.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			for key, want := range test.wantHeaders {
+				assert.Equal(t, want, headers.Values(key), key)
+			}
+
+			headers = nil
+			_, err = GetClusterFromRESTConfig(&rest.Config{Host: "https://unreachable.example.com"}, opts...)
+			require.NoError(t, err)
+			for key, want := range test.wantHeaders {
+				assert.Equal(t, want, headers.Values(key), key)
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	k8sapierror "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/utils/strings/slices"
+
+	"github.com/aquasecurity/trivy-kubernetes/pkg/bom"
+)
+
+const (
+	// ClusterOperatorComponentType is the Type property of BOM components built from OpenShift cluster operators
+	ClusterOperatorComponentType = "clusterOperator"
+	// OLMOperatorComponentType is the Type property of BOM components built from OLM cluster service versions
+	OLMOperatorComponentType = "olmOperator"
+
+	openShiftConfigNamespace = "openshift-config"
+	openShiftPullSecret      = "pull-secret"
+	// olmCopiedFromLabel marks the copies OLM makes of a cluster service version in
+	// every namespace the operator watches
+	olmCopiedFromLabel = "olm.copiedFrom"
+)
+
+var (
+	clusterVersionsResource        = schema.GroupResource{Group: "config.openshift.io", Resource: "clusterversions"}
+	clusterOperatorsResource       = schema.GroupResource{Group: "config.openshift.io", Resource: "clusteroperators"}
+	clusterServiceVersionsResource = schema.GroupResource{Group: "operators.coreos.com", Resource: "clusterserviceversions"}
+
+	// clusterOperatorConditions are the cluster operator conditions recorded as properties
+	clusterOperatorConditions = []string{"Available", "Progressing", "Degraded", "Upgradeable"}
+)
+
+// collectOpenShiftInventory returns the cluster operators and OLM operators as BOM
+// components, and the release and pull secret of the cluster as BOM properties
+func (c *cluster) collectOpenShiftInventory(ctx context.Context) ([]bom.Component, map[string]string, error) {
+	components := make([]bom.Component, 0)
+	properties := make(map[string]string)
+
+	clusterVersions, err := c.listOpenShiftResources(ctx, clusterVersionsResource)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, cv := range clusterVersions {
+		for k, v := range clusterVersionProperties(cv) {
+			properties[k] = v
+		}
+	}
+
+	operators, err := c.listOpenShiftResources(ctx, clusterOperatorsResource)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, co := range operators {
+		components = append(components, clusterOperatorComponent(co))
+	}
+
+	csvs, err := c.listOpenShiftResources(ctx, clusterServiceVersionsResource)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, csv := range csvs {
+		if _, ok := csv.GetLabels()[olmCopiedFromLabel]; ok {
+			continue
+		}
+		components = append(components, clusterServiceVersionComponent(csv))
+	}
+
+	secret, err := c.clientset.CoreV1().Secrets(openShiftConfigNamespace).Get(ctx, openShiftPullSecret, metav1.GetOptions{})
+	switch {
+	case err == nil:
+		properties["PullSecret"] = openShiftConfigNamespace + "/" + openShiftPullSecret
+		if registries := pullSecretRegistries(secret); len(registries) > 0 {
+			properties["PullSecretRegistries"] = strings.Join(registries, ",")
+		}
+	case k8sapierror.IsNotFound(err) || k8sapierror.IsForbidden(err):
+		slog.Warn("Unable to get the cluster pull secret", "error", err)
+	default:
+		return nil, nil, fmt.Errorf("getting pull secret: %w", err)
+	}
+	return components, properties, nil
+}
+
+// listOpenShiftResources lists the resources of a group of the OpenShift api,
+// nothing when the cluster doesn't serve it or they can't be listed
+func (c *cluster) listOpenShiftResources(ctx context.Context, gr schema.GroupResource) ([]unstructured.Unstructured, error) {
+	if c.restMapper == nil || c.dynamicClient == nil {
+		return nil, nil
+	}
+	gvr, err := c.restMapper.ResourceFor(gr.WithVersion(""))
+	if err != nil {
+		if meta.IsNoMatchError(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("resolving %s: %w", gr, err)
+	}
+	resources, err := c.dynamicClient.Resource(gvr).Namespace("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		if k8sapierror.IsNotFound(err) || k8sapierror.IsForbidden(err) {
+			slog.Warn("Unable to list OpenShift resources", "resource", gr.String(), "error", err)
+			return nil, nil
+		}
+		return nil, fmt.Errorf("listing %s: %w", gr, err)
+	}
+	sort.Slice(resources.Items, func(i, j int) bool {
+		if resources.Items[i].GetNamespace() != resources.Items[j].GetNamespace() {
+			return resources.Items[i].GetNamespace() < resources.Items[j].GetNamespace()
+		}
+		return resources.Items[i].GetName() < resources.Items[j].GetName()
+	})
+	return resources.Items, nil
+}
+
+// clusterVersionProperties returns the channel and the release image of the
+// version the cluster runs or is updating to
+func clusterVersionProperties(cv unstructured.Unstructured) map[string]string {
+	properties := make(map[string]string)
+	if version, _, _ := unstructured.NestedString(cv.Object, "status", "desired", "version"); version != "" {
+		properties["OpenShiftVersion"] = version
+	}
+	if channel, _, _ := unstructured.NestedString(cv.Object, "spec", "channel"); channel != "" {
+		properties["OpenShiftChannel"] = channel
+	}
+	if image, _, _ := unstructured.NestedString(cv.Object, "status", "desired", "image"); image != "" {
+		properties["ReleaseImage"] = image
+		if strings.Contains(image, "@") {
+			properties["ReleaseImageDigest"] = extractDigest(image)
+		}
+	}
+	return properties
+}
+
+func clusterOperatorComponent(co unstructured.Unstructured) bom.Component {
+	component := bom.Component{
+		Name: co.GetName(),
+		Properties: map[string]string{
+			"Name": co.GetName(),
+			"Type": ClusterOperatorComponentType,
+		},
+		Resources: []bom.Resource{{Kind: "ClusterOperator", Name: co.GetName()}},
+	}
+	versions, _, _ := unstructured.NestedSlice(co.Object, "status", "versions")
+	for _, v := range versions {
+		version, _ := v.(map[string]interface{})
+		if name, _ := version["name"].(string); name == "operator" {
+			component.Version, _ = version["version"].(string)
+		}
+	}
+	conditions, _, _ := unstructured.NestedSlice(co.Object, "status", "conditions")
+	for _, c := range conditions {
+		condition, _ := c.(map[string]interface{})
+		conditionType, _ := condition["type"].(string)
+		if slices.Contains(clusterOperatorConditions, conditionType) {
+			component.Properties[conditionType], _ = condition["status"].(string)
+		}
+	}
+	return component
+}
+
+func clusterServiceVersionComponent(csv unstructured.Unstructured) bom.Component {
+	version, _, _ := unstructured.NestedString(csv.Object, "spec", "version")
+	component := bom.Component{
+		Namespace: csv.GetNamespace(),
+		Name:      strings.TrimSuffix(csv.GetName(), ".v"+version),
+		Version:   version,
+		Properties: map[string]string{
+			"Name": csv.GetName(),
+			"Type": OLMOperatorComponentType,
+		},
+		Containers: make([]bom.Container, 0),
+		Resources:  []bom.Resource{{Kind: "ClusterServiceVersion", Namespace: csv.GetNamespace(), Name: csv.GetName()}},
+	}
+	if displayName, _, _ := unstructured.NestedString(csv.Object, "spec", "displayName"); displayName != "" {
+		component.Properties["DisplayName"] = displayName
+	}
+	if provider, _, _ := unstructured.NestedString(csv.Object, "spec", "provider", "name"); provider != "" {
+		component.Properties["Provider"] = provider
+	}
+	if phase, _, _ := unstructured.NestedString(csv.Object, "status", "phase"); phase != "" {
+		component.Properties["Phase"] = phase
+	}
+	relatedImages, _, _ := unstructured.NestedSlice(csv.Object, "spec", "relatedImages")
+	for _, ri := range relatedImages {
+		relatedImage, _ := ri.(map[string]interface{})
+		image, _ := relatedImage["image"].(string)
+		container, err := GetContainer(image, image)
+		if err != nil {
+			slog.Debug("Skipping related image without digest", "csv", csv.GetName(), "image", image)
+			continue
+		}
+		component.Containers = append(component.Containers, container)
+	}
+	return component
+}
+
+// pullSecretRegistries returns the registries of a docker config secret, never
+// their credentials
+func pullSecretRegistries(secret *corev1.Secret) []string {
+	var config struct {
+		Auths map[string]json.RawMessage `json:"auths"`
+	}
+	if err := json.Unmarshal(secret.Data[corev1.DockerConfigJsonKey], &config); err != nil {
+		slog.Warn("Unable to decode the cluster pull secret", "error", err)
+		return nil
+	}
+	registries := make([]string, 0, len(config.Auths))
+	for registry := range config.Auths {
+		registries = append(registries, registry)
+	}
+	sort.Strings(registries)
+	return registries
+}
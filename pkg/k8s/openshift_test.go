@@ -0,0 +1,143 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/aquasecurity/trivy-kubernetes/pkg/bom"
+)
+
+const releaseDigest = "sha256:1b2b4ec1a4d4c3c6b1c1f5f4f7f2a7e4e3d0a3cfb6f9dc6a1d6f3b1c2a9e4d5f"
+
+func TestCollectOpenShiftInventory(t *testing.T) {
+	clusterVersion := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "config.openshift.io/v1",
+		"kind":       "ClusterVersion",
+		"metadata":   map[string]interface{}{"name": "version"},
+		"spec":       map[string]interface{}{"channel": "stable-4.16"},
+		"status": map[string]interface{}{
+			"desired": map[string]interface{}{
+				"version": "4.16.3",
+				"image":   "quay.io/openshift-release-dev/ocp-release@" + releaseDigest,
+			},
+		},
+	}}
+	clusterOperator := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "config.openshift.io/v1",
+		"kind":       "ClusterOperator",
+		"metadata":   map[string]interface{}{"name": "kube-apiserver"},
+		"status": map[string]interface{}{
+			"versions": []interface{}{
+				map[string]interface{}{"name": "raw-internal", "version": "4.16.3"},
+				map[string]interface{}{"name": "operator", "version": "4.16.3"},
+			},
+			"conditions": []interface{}{
+				map[string]interface{}{"type": "Available", "status": "True"},
+				map[string]interface{}{"type": "Degraded", "status": "False"},
+				map[string]interface{}{"type": "EvaluationConditionsDetected", "status": "False"},
+			},
+		},
+	}}
+	csv := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "operators.coreos.com/v1alpha1",
+		"kind":       "ClusterServiceVersion",
+		"metadata":   map[string]interface{}{"name": "cert-manager-operator.v1.14.0", "namespace": "cert-manager-operator"},
+		"spec": map[string]interface{}{
+			"version":     "1.14.0",
+			"displayName": "cert-manager Operator for Red Hat OpenShift",
+			"provider":    map[string]interface{}{"name": "Red Hat"},
+			"relatedImages": []interface{}{
+				map[string]interface{}{"name": "operator", "image": "registry.redhat.io/cert-manager/cert-manager-operator-rhel9@" + releaseDigest},
+				map[string]interface{}{"name": "tagged", "image": "registry.redhat.io/cert-manager/cert-manager-rhel9:v1.14.0"},
+			},
+		},
+		"status": map[string]interface{}{"phase": "Succeeded"},
+	}}
+	copiedCSV := csv.DeepCopy()
+	copiedCSV.SetNamespace("apps")
+	copiedCSV.SetLabels(map[string]string{olmCopiedFromLabel: "cert-manager-operator"})
+
+	pullSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: openShiftPullSecret, Namespace: openShiftConfigNamespace},
+		Type:       corev1.SecretTypeDockerConfigJson,
+		Data: map[string][]byte{
+			corev1.DockerConfigJsonKey: []byte(`{"auths":{"registry.redhat.io":{"auth":"c2VjcmV0"},"quay.io":{"auth":"c2VjcmV0"}}}`),
+		},
+	}
+
+	restMapper := meta.NewDefaultRESTMapper(nil)
+	restMapper.Add(schema.GroupVersionKind{Group: "config.openshift.io", Version: "v1", Kind: "ClusterVersion"}, meta.RESTScopeRoot)
+	restMapper.Add(schema.GroupVersionKind{Group: "config.openshift.io", Version: "v1", Kind: "ClusterOperator"}, meta.RESTScopeRoot)
+	restMapper.Add(schema.GroupVersionKind{Group: "operators.coreos.com", Version: "v1alpha1", Kind: "ClusterServiceVersion"}, meta.RESTScopeNamespace)
+	dynamicClient := fakedynamic.NewSimpleDynamicClient(runtime.NewScheme(), clusterVersion, clusterOperator, csv, copiedCSV)
+
+	c, err := NewCluster(fake.NewClientset(pullSecret), dynamicClient, restMapper)
+	require.NoError(t, err)
+	components, properties, err := c.(*cluster).collectOpenShiftInventory(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]string{
+		"OpenShiftVersion":     "4.16.3",
+		"OpenShiftChannel":     "stable-4.16",
+		"ReleaseImage":         "quay.io/openshift-release-dev/ocp-release@" + releaseDigest,
+		"ReleaseImageDigest":   releaseDigest[len("sha256:"):],
+		"PullSecret":           "openshift-config/pull-secret",
+		"PullSecretRegistries": "quay.io,registry.redhat.io",
+	}, properties)
+	assert.Equal(t, []bom.Component{
+		{
+			Name:    "kube-apiserver",
+			Version: "4.16.3",
+			Properties: map[string]string{
+				"Name":      "kube-apiserver",
+				"Type":      ClusterOperatorComponentType,
+				"Available": "True",
+				"Degraded":  "False",
+			},
+			Resources: []bom.Resource{{Kind: "ClusterOperator", Name: "kube-apiserver"}},
+		},
+		{
+			Namespace: "cert-manager-operator",
+			Name:      "cert-manager-operator",
+			Version:   "1.14.0",
+			Properties: map[string]string{
+				"Name":        "cert-manager-operator.v1.14.0",
+				"Type":        OLMOperatorComponentType,
+				"DisplayName": "cert-manager Operator for Red Hat OpenShift",
+				"Provider":    "Red Hat",
+				"Phase":       "Succeeded",
+			},
+			Containers: []bom.Container{
+				{
+					ID:         "cert-manager/cert-manager-operator-rhel9:latest",
+					Version:    "latest",
+					Repository: "cert-manager/cert-manager-operator-rhel9",
+					Registry:   "registry.redhat.io",
+					Digest:     releaseDigest[len("sha256:"):],
+					PURL:       "pkg:oci/cert-manager-operator-rhel9@sha256%3A" + releaseDigest[len("sha256:"):] + "?repository_url=registry.redhat.io%2Fcert-manager%2Fcert-manager-operator-rhel9&tag=latest",
+				},
+			},
+			Resources: []bom.Resource{{Kind: "ClusterServiceVersion", Namespace: "cert-manager-operator", Name: "cert-manager-operator.v1.14.0"}},
+		},
+	}, components)
+}
+
+func TestCollectOpenShiftInventoryNotServed(t *testing.T) {
+	c, err := NewCluster(fake.NewClientset(), fakedynamic.NewSimpleDynamicClient(runtime.NewScheme()), meta.NewDefaultRESTMapper(nil))
+	require.NoError(t, err)
+	components, properties, err := c.(*cluster).collectOpenShiftInventory(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, components)
+	assert.Empty(t, properties)
+}
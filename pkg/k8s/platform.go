@@ -0,0 +1,248 @@
+package k8s
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/utils/strings/slices"
+)
+
+const (
+	oke         = "oke"
+	doks        = "doks"
+	kindCluster = "kind"
+	talos       = "talos"
+	k0s         = "k0s"
+	vcluster    = "vcluster"
+)
+
+// Platform describes the kubernetes distribution or managed service of a cluster
+type Platform struct {
+	Name    string
+	Version string
+	// Evidence lists the signals the platform was detected from
+	Evidence []string
+}
+
+// platformRule lists the signals identifying a platform, any of them is enough
+// unless the platform is managed: self-managed clusters on the VMs of the cloud
+// provider share its provider ID, which only adds to managed-only signals
+type platformRule struct {
+	name               string
+	managed            bool
+	versionMarkers     []string
+	providerIDPrefixes []string
+	nodeLabels         []string
+	nodeLabelPrefixes  []string
+	osImagePrefixes    []string
+	apiGroups          []string
+}
+
+// platformRules are ordered from the most specific: distributions which can run
+// on a cloud provider come before the managed services
+var platformRules = []platformRule{
+	{
+		name:      ocp,
+		apiGroups: []string{"config.openshift.io", "route.openshift.io"},
+	},
+	{
+		name:       vcluster,
+		nodeLabels: []string{"vcluster.loft.sh/fake-node"},
+	},
+	{
+		name:            talos,
+		osImagePrefixes: []string{"Talos"},
+	},
+	{
+		name:           k0s,
+		versionMarkers: []string{"+k0s"},
+		nodeLabels:     []string{"node.k0sproject.io/role"},
+	},
+	{
+		name:               kindCluster,
+		providerIDPrefixes: []string{"kind://"},
+	},
+	{
+		name:           k3s,
+		versionMarkers: []string{"+k3s"},
+		nodeLabels:     []string{"node.kubernetes.io/instance-type=k3s"},
+	},
+	{
+		name:           rke2,
+		versionMarkers: []string{"+rke2", "-rke2"},
+		nodeLabels:     []string{"node.kubernetes.io/instance-type=rke2"},
+	},
+	{
+		name:       microk8s,
+		nodeLabels: []string{"microk8s.io/cluster"},
+	},
+	{
+		name:               eks,
+		managed:            true,
+		versionMarkers:     []string{"-eks-"},
+		providerIDPrefixes: []string{"aws://"},
+		nodeLabelPrefixes:  []string{"eks.amazonaws.com/"},
+	},
+	{
+		name:               gke,
+		managed:            true,
+		versionMarkers:     []string{"-gke."},
+		providerIDPrefixes: []string{"gce://"},
+		nodeLabelPrefixes:  []string{"cloud.google.com/gke-"},
+	},
+	{
+		name:               aks,
+		managed:            true,
+		providerIDPrefixes: []string{"azure://"},
+		nodeLabelPrefixes:  []string{"kubernetes.azure.com/"},
+	},
+	{
+		name:               oke,
+		providerIDPrefixes: []string{"ocid1.instance.", "oci://"},
+		nodeLabels:         []string{"oci.oraclecloud.com/fault-domain"},
+	},
+	{
+		name:               doks,
+		providerIDPrefixes: []string{"digitalocean://"},
+		nodeLabels:         []string{"doks.digitalocean.com/node-id"},
+	},
+}
+
+// Platform returns the cluster platform, plain kubernetes when it can't be detected
+func (c *cluster) Platform() Platform {
+	platform, err := c.Platfrom()
+	if err != nil {
+		return Platform{Name: native, Version: "1.23.0"}
+	}
+	return platform
+}
+
+func (c *cluster) Platfrom() (Platform, error) {
+	ctx := context.Background()
+	serverVersion, err := c.clientset.Discovery().ServerVersion()
+	if err != nil {
+		return Platform{}, err
+	}
+	var nodes []corev1.Node
+	nodeList, err := c.clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		slog.Warn("Unable to list nodes for platform detection", "error", err)
+	} else {
+		nodes = nodeList.Items
+	}
+	var apiGroups []string
+	groups, err := c.clientset.Discovery().ServerGroups()
+	if err != nil {
+		slog.Warn("Unable to list api groups for platform detection", "error", err)
+	} else {
+		for _, g := range groups.Groups {
+			apiGroups = append(apiGroups, g.Name)
+		}
+	}
+
+	platform := detectPlatform(serverVersion.GitVersion, nodes, apiGroups)
+	if v := c.getOpenShiftVersion(ctx); v != "" {
+		if platform.Name != ocp {
+			platform = Platform{Name: ocp}
+		}
+		platform.Version = majorVersion(v)
+		platform.Evidence = append(platform.Evidence, "clusterversion "+v)
+	}
+	return platform, nil
+}
+
+// detectPlatform returns the first platform matching the server version, the
+// nodes provider ID, labels and OS image, or the served api groups
+func detectPlatform(gitVersion string, nodes []corev1.Node, apiGroups []string) Platform {
+	version := getPlatformInfoFromVersion(gitVersion).Version
+	for _, rule := range platformRules {
+		if evidence := rule.evidence(gitVersion, nodes, apiGroups); len(evidence) > 0 {
+			return Platform{Name: rule.name, Version: version, Evidence: evidence}
+		}
+	}
+	return Platform{Name: native, Version: version}
+}
+
+func (r platformRule) evidence(gitVersion string, nodes []corev1.Node, apiGroups []string) []string {
+	evidence := make([]string, 0)
+	// whether a signal other than the provider ID was found
+	confirmed := false
+	add := func(e string) {
+		if !slices.Contains(evidence, e) {
+			evidence = append(evidence, e)
+		}
+	}
+	confirm := func(e string) {
+		confirmed = true
+		add(e)
+	}
+	for _, marker := range r.versionMarkers {
+		if strings.Contains(gitVersion, marker) {
+			confirm("server version " + gitVersion)
+		}
+	}
+	for _, node := range nodes {
+		for _, prefix := range r.providerIDPrefixes {
+			if strings.HasPrefix(node.Spec.ProviderID, prefix) {
+				add("node provider ID " + prefix)
+			}
+		}
+		for _, label := range r.nodeLabels {
+			key, value, hasValue := strings.Cut(label, "=")
+			if v, ok := node.Labels[key]; ok && (!hasValue || v == value) {
+				confirm("node label " + label)
+			}
+		}
+		var keys []string
+		for key := range node.Labels {
+			for _, prefix := range r.nodeLabelPrefixes {
+				if strings.HasPrefix(key, prefix) {
+					keys = append(keys, key)
+				}
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			confirm("node label " + key)
+		}
+		for _, prefix := range r.osImagePrefixes {
+			if strings.HasPrefix(node.Status.NodeInfo.OSImage, prefix) {
+				confirm("node os image " + node.Status.NodeInfo.OSImage)
+			}
+		}
+	}
+	for _, group := range r.apiGroups {
+		if slices.Contains(apiGroups, group) {
+			confirm("api group " + group)
+		}
+	}
+	if r.managed && !confirmed {
+		return nil
+	}
+	return evidence
+}
+
+func (c *cluster) getOpenShiftVersion(ctx context.Context) string {
+	if c.restMapper == nil {
+		return ""
+	}
+	gvr, err := c.restMapper.ResourceFor(schema.GroupVersionResource{Group: "config.openshift.io", Resource: "clusterversions"})
+	if err != nil {
+		return ""
+	}
+	resources, err := c.dynamicClient.Resource(gvr).Namespace("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return ""
+	}
+	var version string
+	for _, resource := range resources.Items {
+		version, _, _ = unstructured.NestedString(resource.Object, "status", "desired", "version")
+	}
+	return version
+}
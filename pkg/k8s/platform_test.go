@@ -0,0 +1,155 @@
+package k8s
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDetectPlatform(t *testing.T) {
+	node := func(name, providerID string, labels map[string]string, osImage string) corev1.Node {
+		return corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+			Spec:       corev1.NodeSpec{ProviderID: providerID},
+			Status:     corev1.NodeStatus{NodeInfo: corev1.NodeSystemInfo{OSImage: osImage}},
+		}
+	}
+	tests := []struct {
+		name       string
+		gitVersion string
+		nodes      []corev1.Node
+		apiGroups  []string
+		expected   Platform
+	}{
+		{
+			name:       "no nodes",
+			gitVersion: "v1.30.2",
+			expected:   Platform{Name: "k8s", Version: "1.30"},
+		},
+		{
+			name:       "eks from version and provider id",
+			gitVersion: "v1.29.1-eks-b9c9ed7",
+			nodes:      []corev1.Node{node("ip-10-0-1-12.ec2.internal", "aws:///us-east-1a/i-0abc", nil, "Amazon Linux 2")},
+			expected: Platform{Name: "eks", Version: "1.29", Evidence: []string{
+				"server version v1.29.1-eks-b9c9ed7",
+				"node provider ID aws://",
+			}},
+		},
+		{
+			name:       "aks node named like eks",
+			gitVersion: "v1.28.5",
+			nodes: []corev1.Node{node("geeks-pool-0", "azure:///subscriptions/abc/vm-0",
+				map[string]string{"kubernetes.azure.com/cluster": "MC_rg_geeks"}, "Ubuntu 22.04.4 LTS")},
+			expected: Platform{Name: "aks", Version: "1.28", Evidence: []string{
+				"node provider ID azure://",
+				"node label kubernetes.azure.com/cluster",
+			}},
+		},
+		{
+			name:       "eks from node labels",
+			gitVersion: "v1.30.4",
+			nodes: []corev1.Node{node("ip-10-0-1-13.ec2.internal", "aws:///us-east-1a/i-0abd",
+				map[string]string{"eks.amazonaws.com/nodegroup": "default", "eks.amazonaws.com/capacityType": "ON_DEMAND"}, "Bottlerocket OS 1.20.0")},
+			expected: Platform{Name: "eks", Version: "1.30", Evidence: []string{
+				"node provider ID aws://",
+				"node label eks.amazonaws.com/capacityType",
+				"node label eks.amazonaws.com/nodegroup",
+			}},
+		},
+		{
+			name:       "kubeadm on aws",
+			gitVersion: "v1.30.4",
+			nodes: []corev1.Node{node("ip-10-0-1-14.ec2.internal", "aws:///us-east-1a/i-0abe",
+				map[string]string{"node.kubernetes.io/instance-type": "m5.large", "topology.kubernetes.io/zone": "us-east-1a"}, "Ubuntu 22.04.4 LTS")},
+			expected: Platform{Name: "k8s", Version: "1.30"},
+		},
+		{
+			name:       "kubeadm on gce",
+			gitVersion: "v1.30.4",
+			nodes: []corev1.Node{node("worker-0", "gce://project/us-central1-a/worker-0",
+				map[string]string{"cloud.google.com/machine-family": "e2"}, "Ubuntu 22.04.4 LTS")},
+			expected: Platform{Name: "k8s", Version: "1.30"},
+		},
+		{
+			name:       "kubeadm on azure",
+			gitVersion: "v1.30.4",
+			nodes: []corev1.Node{node("worker-0", "azure:///subscriptions/abc/resourceGroups/rg/providers/Microsoft.Compute/virtualMachines/worker-0",
+				map[string]string{"topology.kubernetes.io/region": "westeurope"}, "Ubuntu 22.04.4 LTS")},
+			expected: Platform{Name: "k8s", Version: "1.30"},
+		},
+		{
+			name:       "rke2 on aws",
+			gitVersion: "v1.30.4+rke2r1",
+			nodes:      []corev1.Node{node("rancher-0", "aws:///us-east-1a/i-0abf", nil, "SUSE Linux Enterprise Server 15 SP5")},
+			expected:   Platform{Name: "rke2", Version: "1.30", Evidence: []string{"server version v1.30.4+rke2r1"}},
+		},
+		{
+			name:       "gke from version",
+			gitVersion: "v1.28.3-gke.1203001",
+			expected:   Platform{Name: "gke", Version: "1.28", Evidence: []string{"server version v1.28.3-gke.1203001"}},
+		},
+		{
+			name:       "oke",
+			gitVersion: "v1.29.1",
+			nodes:      []corev1.Node{node("10.0.10.2", "ocid1.instance.oc1.iad.abc", nil, "Oracle Linux Server 8.9")},
+			expected:   Platform{Name: "oke", Version: "1.29", Evidence: []string{"node provider ID ocid1.instance."}},
+		},
+		{
+			name:       "doks",
+			gitVersion: "v1.29.1",
+			nodes:      []corev1.Node{node("pool-1", "digitalocean://4242", map[string]string{"doks.digitalocean.com/node-id": "x"}, "Debian GNU/Linux 12")},
+			expected: Platform{Name: "doks", Version: "1.29", Evidence: []string{
+				"node provider ID digitalocean://",
+				"node label doks.digitalocean.com/node-id",
+			}},
+		},
+		{
+			name:       "kind",
+			gitVersion: "v1.30.0",
+			nodes:      []corev1.Node{node("kind-control-plane", "kind://docker/kind/kind-control-plane", nil, "Debian GNU/Linux 12")},
+			expected:   Platform{Name: "kind", Version: "1.30", Evidence: []string{"node provider ID kind://"}},
+		},
+		{
+			name:       "talos on aws",
+			gitVersion: "v1.30.1",
+			nodes:      []corev1.Node{node("talos-cp-1", "aws:///eu-west-1a/i-0def", nil, "Talos (v1.7.5)")},
+			expected:   Platform{Name: "talos", Version: "1.30", Evidence: []string{"node os image Talos (v1.7.5)"}},
+		},
+		{
+			name:       "k0s",
+			gitVersion: "v1.30.2+k0s",
+			expected:   Platform{Name: "k0s", Version: "1.30", Evidence: []string{"server version v1.30.2+k0s"}},
+		},
+		{
+			name:       "vcluster backed by k3s",
+			gitVersion: "v1.29.0+k3s1",
+			nodes:      []corev1.Node{node("worker-1", "", map[string]string{"vcluster.loft.sh/fake-node": "true"}, "")},
+			expected:   Platform{Name: "vcluster", Version: "1.29", Evidence: []string{"node label vcluster.loft.sh/fake-node"}},
+		},
+		{
+			name:       "k3s",
+			gitVersion: "v1.29.0+k3s1",
+			nodes:      []corev1.Node{node("server", "k3s://server", map[string]string{"node.kubernetes.io/instance-type": "k3s"}, "")},
+			expected: Platform{Name: "k3s", Version: "1.29", Evidence: []string{
+				"server version v1.29.0+k3s1",
+				"node label node.kubernetes.io/instance-type=k3s",
+			}},
+		},
+		{
+			name:       "openshift api groups",
+			gitVersion: "v1.27.6+b49f9d1",
+			apiGroups:  []string{"apps", "config.openshift.io", "route.openshift.io"},
+			expected: Platform{Name: "ocp", Version: "1.27", Evidence: []string{
+				"api group config.openshift.io",
+				"api group route.openshift.io",
+			}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, detectPlatform(test.gitVersion, test.nodes, test.apiGroups))
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package k8s
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	k8sapierror "k8s.io/apimachinery/pkg/api/errors"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/rest"
+)
+
+// RetryPolicy retries the api server requests failing with a transient error:
+// throttling, unavailability and connection errors of idempotent requests. A
+// Retry-After of the response is waited for, up to MaxBackoff, and removed from
+// the last response so that client-go doesn't retry it again
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts of a request, 1 disables retries
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry, doubled on every retry
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between two attempts, Retry-After included
+	MaxBackoff time.Duration
+	// Jitter randomizes the delays by up to this fraction of them
+	Jitter float64
+	// OnRetry is called before every retry, e.g. to report the attempts
+	OnRetry func(RetryAttempt)
+}
+
+// RetryAttempt describes a failed attempt which is retried
+type RetryAttempt struct {
+	Method  string
+	URL     string
+	Attempt int
+	Delay   time.Duration
+	// StatusCode is the response status, 0 when the request failed
+	StatusCode int
+	Err        error
+}
+
+type retryObserverKey struct{}
+
+// ContextWithRetryObserver returns a context whose api server requests report
+// their retries to the observer, in addition to the OnRetry of the policy
+func ContextWithRetryObserver(ctx context.Context, observer func(RetryAttempt)) context.Context {
+	return context.WithValue(ctx, retryObserverKey{}, observer)
+}
+
+// IsTransientError reports whether an api server error is transient, i.e. the
+// request may succeed later
+func IsTransientError(err error) bool {
+	return k8sapierror.IsTooManyRequests(err) || k8sapierror.IsServiceUnavailable(err) ||
+		k8sapierror.IsServerTimeout(err) || k8sapierror.IsTimeout(err) ||
+		utilnet.IsConnectionReset(err) || utilnet.IsProbableEOF(err) || utilnet.IsTimeout(err)
+}
+
+// DefaultRetryPolicy is the policy of the clusters created without WithRetryPolicy
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    5,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     30 * time.Second,
+		Jitter:         0.2,
+	}
+}
+
+// WithRetryPolicy sets the retry policy of the cluster requests, it replaces the default one
+func WithRetryPolicy(policy RetryPolicy) ClusterOption {
+	return func(o *genericclioptions.ConfigFlags) {
+		o.WrapConfigFn = combineConfigFns(o.WrapConfigFn, policy.wrapConfig)
+	}
+}
+
+func (p RetryPolicy) wrapConfig(c *rest.Config) *rest.Config {
+	c.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+		// a later policy replaces the earlier one instead of multiplying attempts
+		if r, ok := rt.(*retryRoundTripper); ok {
+			rt = r.next
+		}
+		return &retryRoundTripper{next: rt, policy: p}
+	})
+	return c
+}
+
+// backoff returns the delay before the retry following the attempt
+func (p RetryPolicy) backoff(attempt int, retryAfter time.Duration) time.Duration {
+	delay := p.InitialBackoff
+	for i := 1; i < attempt && (p.MaxBackoff <= 0 || delay < p.MaxBackoff); i++ {
+		delay *= 2
+	}
+	if p.Jitter > 0 {
+		delay += time.Duration(float64(delay) * p.Jitter * (2*rand.Float64() - 1))
+	}
+	delay = max(delay, retryAfter)
+	if p.MaxBackoff > 0 {
+		delay = min(delay, p.MaxBackoff)
+	}
+	return delay
+}
+
+type retryRoundTripper struct {
+	next   http.RoundTripper
+	policy RetryPolicy
+}
+
+func (r *retryRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		resp, err := r.next.RoundTrip(req)
+		transient := isTransient(req, resp, err)
+		if attempt >= r.policy.MaxAttempts || !transient {
+			// the retries are exhausted, client-go would retry on a Retry-After again
+			if transient && attempt > 1 && resp != nil {
+				resp.Header.Del("Retry-After")
+			}
+			return resp, err
+		}
+		// the body of the request can't be sent again
+		if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+			return resp, err
+		}
+
+		retry := RetryAttempt{
+			Method:  req.Method,
+			URL:     req.URL.Redacted(),
+			Attempt: attempt,
+			Err:     err,
+		}
+		var retryAfter time.Duration
+		if resp != nil {
+			retry.StatusCode = resp.StatusCode
+			retryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		retry.Delay = r.policy.backoff(attempt, retryAfter)
+		slog.Warn("Retrying api server request", "method", retry.Method, "url", retry.URL,
+			"attempt", retry.Attempt, "status", retry.StatusCode, "delay", retry.Delay, "error", retry.Err)
+		if r.policy.OnRetry != nil {
+			r.policy.OnRetry(retry)
+		}
+		if observer, ok := req.Context().Value(retryObserverKey{}).(func(RetryAttempt)); ok {
+			observer(retry)
+		}
+
+		timer := time.NewTimer(retry.Delay)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
+
+// isTransient reports whether the request failed without being processed, or
+// can be sent again without side effects
+func isTransient(req *http.Request, resp *http.Response, err error) bool {
+	idempotent := req.Method == http.MethodGet || req.Method == http.MethodHead
+	if err != nil {
+		return idempotent && (utilnet.IsConnectionReset(err) || utilnet.IsProbableEOF(err) || utilnet.IsTimeout(err))
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true
+	case http.StatusBadGateway, http.StatusGatewayTimeout:
+		return idempotent
+	}
+	return false
+}
+
+// parseRetryAfter parses a Retry-After header in seconds or as an HTTP date
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0)
+	}
+	return 0
+}
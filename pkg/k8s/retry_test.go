@@ -0,0 +1,268 @@
+package k8s
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/rest"
+)
+
+func TestRetryRoundTripper(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		statuses     []int
+		retryAfter   string
+		maxAttempts  int
+		wantStatus   int
+		wantAttempts int
+	}{
+		{
+			name:         "throttled then served",
+			method:       http.MethodGet,
+			statuses:     []int{http.StatusTooManyRequests, http.StatusServiceUnavailable, http.StatusOK},
+			maxAttempts:  5,
+			wantStatus:   http.StatusOK,
+			wantAttempts: 3,
+		},
+		{
+			name:         "throttled with Retry-After",
+			method:       http.MethodGet,
+			statuses:     []int{http.StatusTooManyRequests, http.StatusOK},
+			retryAfter:   "1",
+			maxAttempts:  5,
+			wantStatus:   http.StatusOK,
+			wantAttempts: 2,
+		},
+		{
+			name:         "throttled with Retry-After until exhausted",
+			method:       http.MethodGet,
+			statuses:     []int{http.StatusTooManyRequests, http.StatusTooManyRequests},
+			retryAfter:   "1",
+			maxAttempts:  2,
+			wantStatus:   http.StatusTooManyRequests,
+			wantAttempts: 2,
+		},
+		{
+			name:         "unavailable until a date",
+			method:       http.MethodGet,
+			statuses:     []int{http.StatusServiceUnavailable, http.StatusOK},
+			retryAfter:   "Thu, 01 Jan 1970 00:00:00 GMT",
+			maxAttempts:  5,
+			wantStatus:   http.StatusOK,
+			wantAttempts: 2,
+		},
+		{
+			name:         "attempts exhausted",
+			method:       http.MethodGet,
+			statuses:     []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable},
+			maxAttempts:  2,
+			wantStatus:   http.StatusServiceUnavailable,
+			wantAttempts: 2,
+		},
+		{
+			name:         "retries disabled",
+			method:       http.MethodGet,
+			statuses:     []int{http.StatusTooManyRequests, http.StatusOK},
+			maxAttempts:  1,
+			wantStatus:   http.StatusTooManyRequests,
+			wantAttempts: 1,
+		},
+		{
+			name:         "not transient",
+			method:       http.MethodGet,
+			statuses:     []int{http.StatusForbidden, http.StatusOK},
+			maxAttempts:  5,
+			wantStatus:   http.StatusForbidden,
+			wantAttempts: 1,
+		},
+		{
+			name:         "throttled create",
+			method:       http.MethodPost,
+			statuses:     []int{http.StatusTooManyRequests, http.StatusCreated},
+			maxAttempts:  5,
+			wantStatus:   http.StatusCreated,
+			wantAttempts: 2,
+		},
+		{
+			name:         "gateway timeout of a create",
+			method:       http.MethodPost,
+			statuses:     []int{http.StatusGatewayTimeout, http.StatusCreated},
+			maxAttempts:  5,
+			wantStatus:   http.StatusGatewayTimeout,
+			wantAttempts: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var bodies []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				bodies = append(bodies, string(body))
+				if test.retryAfter != "" {
+					w.Header().Set("Retry-After", test.retryAfter)
+				}
+				w.WriteHeader(test.statuses[len(bodies)-1])
+			}))
+			defer server.Close()
+
+			var retries []RetryAttempt
+			policy := RetryPolicy{
+				MaxAttempts: test.maxAttempts,
+				MaxBackoff:  10 * time.Millisecond,
+				OnRetry:     func(a RetryAttempt) { retries = append(retries, a) },
+			}
+			client := &http.Client{Transport: &retryRoundTripper{next: http.DefaultTransport, policy: policy}}
+			req, err := http.NewRequest(test.method, server.URL+"/api/v1/pods", strings.NewReader("body"))
+			require.NoError(t, err)
+			resp, err := client.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, test.wantStatus, resp.StatusCode)
+			if test.wantAttempts > 1 && test.wantStatus != http.StatusOK {
+				assert.Empty(t, resp.Header.Get("Retry-After"), "left to client-go once the attempts are exhausted")
+			}
+			assert.Len(t, bodies, test.wantAttempts)
+			for _, body := range bodies {
+				assert.Equal(t, "body", body)
+			}
+			require.Len(t, retries, test.wantAttempts-1)
+			for i, retry := range retries {
+				assert.Equal(t, i+1, retry.Attempt)
+				assert.Equal(t, test.statuses[i], retry.StatusCode)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second}
+	assert.Equal(t, time.Second, policy.backoff(1, 0))
+	assert.Equal(t, 4*time.Second, policy.backoff(3, 0))
+	assert.Equal(t, 5*time.Second, policy.backoff(10, 0))
+	assert.Equal(t, 3*time.Second, policy.backoff(1, 3*time.Second))
+	assert.Equal(t, 5*time.Second, policy.backoff(1, time.Minute))
+
+	policy.Jitter = 0.5
+	for range 10 {
+		delay := policy.backoff(2, 0)
+		assert.GreaterOrEqual(t, delay, time.Second)
+		assert.LessOrEqual(t, delay, 3*time.Second)
+	}
+
+	assert.Equal(t, 2*time.Second, parseRetryAfter("2"))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("soon"))
+}
+
+func TestGetClusterFromRESTConfigRetries(t *testing.T) {
+	var mu sync.Mutex
+	failures := 1
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"gitVersion":"v1.31.2"}`))
+	}))
+	defer server.Close()
+
+	var retries []RetryAttempt
+	c, err := GetClusterFromRESTConfig(&rest.Config{Host: server.URL}, WithRetryPolicy(RetryPolicy{
+		MaxAttempts: 3,
+		OnRetry:     func(a RetryAttempt) { retries = append(retries, a) },
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, "1.31.2", c.GetClusterVersion())
+	require.Len(t, retries, 1)
+	assert.Equal(t, http.StatusServiceUnavailable, retries[0].StatusCode)
+	assert.Equal(t, server.URL+"/version", retries[0].URL)
+}
+
+func TestRetryPolicyRetryAfter(t *testing.T) {
+	tests := []struct {
+		name         string
+		maxAttempts  int
+		wantRequests int
+		wantRetries  int
+	}{
+		{
+			name:         "retried by the policy only",
+			maxAttempts:  5,
+			wantRequests: 5,
+			wantRetries:  4,
+		},
+		{
+			name:         "retries disabled leave client-go retries",
+			maxAttempts:  1,
+			wantRequests: 11,
+			wantRetries:  0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var mu sync.Mutex
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				requests++
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+			}))
+			defer server.Close()
+
+			var retries []RetryAttempt
+			_, err := GetClusterFromRESTConfig(&rest.Config{Host: server.URL}, WithRetryPolicy(RetryPolicy{
+				MaxAttempts: test.maxAttempts,
+				OnRetry:     func(a RetryAttempt) { retries = append(retries, a) },
+			}))
+			require.Error(t, err)
+			assert.Equal(t, test.wantRequests, requests)
+			assert.Len(t, retries, test.wantRetries)
+			for _, retry := range retries {
+				assert.Equal(t, http.StatusTooManyRequests, retry.StatusCode)
+			}
+		})
+	}
+}
+
+func TestContextWithRetryObserver(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	var policyRetries, observed []RetryAttempt
+	policy := RetryPolicy{MaxAttempts: 3, OnRetry: func(a RetryAttempt) { policyRetries = append(policyRetries, a) }}
+	client := &http.Client{Transport: &retryRoundTripper{next: http.DefaultTransport, policy: policy}}
+	ctx := ContextWithRetryObserver(context.Background(), func(a RetryAttempt) { observed = append(observed, a) })
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, http.NoBody)
+	require.NoError(t, err)
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Len(t, observed, 1)
+	assert.Equal(t, http.StatusServiceUnavailable, observed[0].StatusCode)
+	assert.Equal(t, policyRetries, observed)
+}
@@ -0,0 +1,178 @@
+package trivyk8s
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+
+	"github.com/aquasecurity/trivy-kubernetes/pkg/artifacts"
+	"github.com/aquasecurity/trivy-kubernetes/pkg/bom"
+	"github.com/aquasecurity/trivy-kubernetes/pkg/k8s"
+)
+
+const defaultClusterConcurrency = 5
+
+// ClusterScan is the result of scanning one cluster, Err is set when the cluster
+// could not be scanned
+type ClusterScan struct {
+	Cluster   artifacts.ClusterIdentity
+	Artifacts []*artifacts.Artifact
+	Bom       *bom.Result
+	Err       error
+}
+
+type multiCluster struct {
+	contexts       []string
+	clusterOptions []k8s.ClusterOption
+	k8sOptions     []K8sOption
+	concurrency    int
+	artifacts      bool
+	clusterBom     bool
+	getCluster     func(opts ...k8s.ClusterOption) (k8s.Cluster, error)
+}
+
+type MultiClusterOption func(*multiCluster)
+
+// WithContexts scans the given kubeconfig contexts instead of all of them
+func WithContexts(contexts ...string) MultiClusterOption {
+	return func(m *multiCluster) {
+		m.contexts = contexts
+	}
+}
+
+// WithClusterOptions configures the cluster of every context, such as the kubeconfig path
+func WithClusterOptions(opts ...k8s.ClusterOption) MultiClusterOption {
+	return func(m *multiCluster) {
+		m.clusterOptions = append(m.clusterOptions, opts...)
+	}
+}
+
+// WithK8sOptions configures the artifacts listing of every cluster
+func WithK8sOptions(opts ...K8sOption) MultiClusterOption {
+	return func(m *multiCluster) {
+		m.k8sOptions = append(m.k8sOptions, opts...)
+	}
+}
+
+// WithClusterConcurrency sets how many clusters are scanned at the same time, 5 by default
+func WithClusterConcurrency(concurrency int) MultiClusterOption {
+	return func(m *multiCluster) {
+		m.concurrency = concurrency
+	}
+}
+
+// WithArtifacts enables the artifacts listing of every cluster, enabled by default
+func WithArtifacts(artifacts bool) MultiClusterOption {
+	return func(m *multiCluster) {
+		m.artifacts = artifacts
+	}
+}
+
+// WithClusterBom enables the BOM collection of every cluster, enabled by default
+func WithClusterBom(clusterBom bool) MultiClusterOption {
+	return func(m *multiCluster) {
+		m.clusterBom = clusterBom
+	}
+}
+
+// ScanClusters lists the artifacts and collects the BOM of every kubeconfig context
+// concurrently. A cluster failure is reported in its ClusterScan and doesn't stop
+// the other scans; scans are returned in the contexts order, sorted by name when
+// all contexts are scanned
+func ScanClusters(ctx context.Context, opts ...MultiClusterOption) ([]ClusterScan, error) {
+	m := &multiCluster{
+		concurrency: defaultClusterConcurrency,
+		artifacts:   true,
+		clusterBom:  true,
+		getCluster:  k8s.GetCluster,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m.scan(ctx)
+}
+
+func (m *multiCluster) scan(ctx context.Context) ([]ClusterScan, error) {
+	kubeContexts, err := k8s.GetKubeConfigContexts(m.clusterOptions...)
+	if err != nil {
+		return nil, err
+	}
+	clusterNames := make(map[string]string, len(kubeContexts))
+	for _, c := range kubeContexts {
+		clusterNames[c.Name] = c.Cluster
+	}
+	contexts := m.contexts
+	if len(contexts) == 0 {
+		for _, c := range kubeContexts {
+			contexts = append(contexts, c.Name)
+		}
+	}
+
+	scans := make([]ClusterScan, len(contexts))
+	concurrency := max(m.concurrency, 1)
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, kubeContext := range contexts {
+		scans[i].Cluster = artifacts.ClusterIdentity{Context: kubeContext, Name: clusterNames[kubeContext]}
+		wg.Add(1)
+		go func(scan *ClusterScan) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			m.scanCluster(ctx, scan)
+		}(&scans[i])
+	}
+	wg.Wait()
+	return scans, nil
+}
+
+func (m *multiCluster) scanCluster(ctx context.Context, scan *ClusterScan) {
+	if err := ctx.Err(); err != nil {
+		scan.Err = err
+		return
+	}
+	if scan.Cluster.Name == "" {
+		scan.Err = fmt.Errorf("context %q not found in kubeconfig", scan.Cluster.Context)
+		return
+	}
+	// clipped so that the concurrent scans don't share the spare capacity of the options
+	opts := append(slices.Clip(m.clusterOptions), k8s.WithContext(scan.Cluster.Context))
+	cluster, err := m.getCluster(opts...)
+	if err != nil {
+		scan.Err = fmt.Errorf("connecting to cluster %q: %w", scan.Cluster.Context, err)
+		return
+	}
+	// the options, like the component catalog, apply to the BOM collection too
+	k8sClient := New(cluster, m.k8sOptions...).(*client)
+	if id, err := cluster.GetClusterID(ctx); err != nil {
+		slog.Warn("Unable to identify cluster", "context", scan.Cluster.Context, "error", err)
+	} else {
+		scan.Cluster.ID = id
+	}
+	if m.clusterBom {
+		b, err := cluster.CreateClusterBom(ctx, k8sClient.bomOptions()...)
+		if err != nil {
+			scan.Err = fmt.Errorf("collecting bom of cluster %q: %w", scan.Cluster.Context, err)
+			return
+		}
+		if b.Properties == nil {
+			b.Properties = make(map[string]string)
+		}
+		b.Properties["Context"] = scan.Cluster.Context
+		scan.Bom = b
+	}
+	if m.artifacts {
+		artifactList, err := k8sClient.ListArtifacts(ctx)
+		if err != nil {
+			scan.Err = fmt.Errorf("listing artifacts of cluster %q: %w", scan.Cluster.Context, err)
+			return
+		}
+		for _, a := range artifactList {
+			identity := scan.Cluster
+			a.Cluster = &identity
+		}
+		scan.Artifacts = artifactList
+	}
+}
@@ -0,0 +1,162 @@
+package trivyk8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	"github.com/aquasecurity/trivy-kubernetes/pkg/artifacts"
+	"github.com/aquasecurity/trivy-kubernetes/pkg/k8s"
+	"github.com/aquasecurity/trivy-kubernetes/pkg/k8s/fake"
+)
+
+func writeKubeConfig(t *testing.T, contexts ...string) string {
+	config := clientcmdapi.NewConfig()
+	config.AuthInfos["user"] = &clientcmdapi.AuthInfo{Token: "token"}
+	for _, name := range contexts {
+		config.Clusters[name+"-cluster"] = &clientcmdapi.Cluster{Server: "https://" + name + ".example.com"}
+		config.Contexts[name] = &clientcmdapi.Context{Cluster: name + "-cluster", AuthInfo: "user"}
+	}
+	kubeConfig := filepath.Join(t.TempDir(), "config")
+	require.NoError(t, clientcmd.WriteToFile(*config, kubeConfig))
+	return kubeConfig
+}
+
+func TestScanClusters(t *testing.T) {
+	kubeConfig := writeKubeConfig(t, "prod", "dev", "broken")
+	clusters := make(map[string]k8s.Cluster)
+	for name, deployment := range map[string]string{"prod": "api", "dev": "web"} {
+		cluster, err := fake.NewCluster(fake.WithYAML(`
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: kube-system
+  uid: ` + name + `-uid
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: ` + deployment + `
+  namespace: app
+spec:
+  template:
+    spec:
+      containers:
+        - name: app
+          image: ghcr.io/acme/app:1.0
+`))
+		require.NoError(t, err)
+		clusters[name] = cluster
+	}
+
+	tests := []struct {
+		name     string
+		contexts []string
+		want     map[string]string
+		wantErrs map[string]bool
+	}{
+		{
+			name:     "all contexts",
+			want:     map[string]string{"broken": "", "dev": "web", "prod": "api"},
+			wantErrs: map[string]bool{"broken": true},
+		},
+		{
+			name:     "selected contexts",
+			contexts: []string{"prod", "missing"},
+			want:     map[string]string{"prod": "api", "missing": ""},
+			wantErrs: map[string]bool{"missing": true},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m := &multiCluster{
+				contexts:       test.contexts,
+				clusterOptions: []k8s.ClusterOption{k8s.WithKubeConfig(kubeConfig)},
+				concurrency:    2,
+				artifacts:      true,
+				clusterBom:     true,
+				getCluster: func(opts ...k8s.ClusterOption) (k8s.Cluster, error) {
+					cf := genericclioptions.NewConfigFlags(true)
+					for _, opt := range opts {
+						opt(cf)
+					}
+					if c, ok := clusters[*cf.Context]; ok {
+						return c, nil
+					}
+					return nil, errors.New("unreachable")
+				},
+			}
+			scans, err := m.scan(context.Background())
+			require.NoError(t, err)
+
+			got := make(map[string]string)
+			for _, scan := range scans {
+				assert.Equal(t, test.wantErrs[scan.Cluster.Context], scan.Err != nil, scan.Cluster.Context)
+				got[scan.Cluster.Context] = ""
+				if scan.Err != nil {
+					continue
+				}
+				require.NotNil(t, scan.Bom)
+				assert.Equal(t, scan.Cluster.Context, scan.Bom.Properties["Context"])
+				assert.Equal(t, "kube-system:"+scan.Cluster.Context+"-uid", scan.Bom.Properties["ClusterID"])
+				for _, a := range scan.Artifacts {
+					assert.Equal(t, &artifacts.ClusterIdentity{
+						ID:      "kube-system:" + scan.Cluster.Context + "-uid",
+						Context: scan.Cluster.Context,
+						Name:    scan.Cluster.Context + "-cluster",
+					}, a.Cluster)
+					if a.Kind == "Deployment" {
+						got[scan.Cluster.Context] = a.Name
+					}
+				}
+			}
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestScanClustersOwnContext(t *testing.T) {
+	contexts := []string{"prod", "dev", "staging"}
+	kubeConfig := writeKubeConfig(t, contexts...)
+
+	m := &multiCluster{concurrency: len(contexts)}
+	for _, opt := range []MultiClusterOption{
+		// options appended several times leave spare capacity
+		WithClusterOptions(k8s.WithKubeConfig(kubeConfig)),
+		WithClusterOptions(k8s.WithQPS(50)),
+		WithClusterOptions(k8s.WithBurst(100)),
+	} {
+		opt(m)
+	}
+	require.Greater(t, cap(m.clusterOptions), len(m.clusterOptions))
+
+	// every scan waits for the others before applying its options
+	var connecting sync.WaitGroup
+	connecting.Add(len(contexts))
+	m.getCluster = func(opts ...k8s.ClusterOption) (k8s.Cluster, error) {
+		connecting.Done()
+		connecting.Wait()
+		cf := genericclioptions.NewConfigFlags(true)
+		for _, opt := range opts {
+			opt(cf)
+		}
+		return nil, fmt.Errorf("connected to %s", *cf.Context)
+	}
+
+	scans, err := m.scan(context.Background())
+	require.NoError(t, err)
+	require.Len(t, scans, len(contexts))
+	for _, scan := range scans {
+		assert.ErrorContains(t, scan.Err, "connected to "+scan.Cluster.Context)
+	}
+}
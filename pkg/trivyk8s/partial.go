@@ -0,0 +1,70 @@
+package trivyk8s
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/aquasecurity/trivy-kubernetes/pkg/k8s"
+)
+
+// PartialResults reports the api server requests retried by the last listing
+// and, with WithPartialResults, what it left out once the retries were exhausted
+type PartialResults struct {
+	Retries  []k8s.RetryAttempt `json:"retries,omitempty"`
+	Failures []ScanFailure      `json:"failures,omitempty"`
+}
+
+// ScanFailure is a resource of a namespace, or a node, left out of the results,
+// an empty namespace stands for all namespaces or a cluster resource
+type ScanFailure struct {
+	Resource  string `json:"resource"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Error     string `json:"error"`
+}
+
+// Complete reports whether nothing was left out of the results
+func (r *PartialResults) Complete() bool {
+	return len(r.Failures) == 0
+}
+
+// PartialResults returns the report of the last listing
+func (c *client) PartialResults() *PartialResults {
+	c.reportMu.Lock()
+	defer c.reportMu.Unlock()
+	report := &PartialResults{}
+	if c.report != nil {
+		report.Retries = append(report.Retries, c.report.Retries...)
+		report.Failures = append(report.Failures, c.report.Failures...)
+	}
+	return report
+}
+
+// startReport resets the report and returns a context recording the retries in it
+func (c *client) startReport(ctx context.Context) context.Context {
+	c.reportMu.Lock()
+	c.report = &PartialResults{}
+	c.reportMu.Unlock()
+	return k8s.ContextWithRetryObserver(ctx, func(attempt k8s.RetryAttempt) {
+		c.reportMu.Lock()
+		defer c.reportMu.Unlock()
+		c.report.Retries = append(c.report.Retries, attempt)
+	})
+}
+
+// leaveOut records the failure when partial results are enabled and reports
+// whether the listing goes on without the failed resource
+func (c *client) leaveOut(failure ScanFailure) bool {
+	if !c.partialResults {
+		return false
+	}
+	slog.Warn("Leaving out of the results", "resource", failure.Resource,
+		"namespace", failure.Namespace, "name", failure.Name, "error", failure.Error)
+	c.reportMu.Lock()
+	defer c.reportMu.Unlock()
+	if c.report == nil {
+		c.report = &PartialResults{}
+	}
+	c.report.Failures = append(c.report.Failures, failure)
+	return true
+}
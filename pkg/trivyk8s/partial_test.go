@@ -0,0 +1,92 @@
+package trivyk8s
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	k8sapierror "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/aquasecurity/trivy-kubernetes/pkg/k8s/fake"
+)
+
+const partialClusterYAML = `
+apiVersion: v1
+kind: Pod
+metadata:
+  name: web
+  namespace: app
+spec:
+  containers:
+  - name: web
+    image: nginx:1.27
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: app
+`
+
+func TestPartialResults(t *testing.T) {
+	tests := []struct {
+		name           string
+		partialResults bool
+		listErr        error
+		wantErr        bool
+		wantFailures   []ScanFailure
+	}{
+		{
+			name:    "transient error fails the listing",
+			listErr: k8sapierror.NewServiceUnavailable("overloaded"),
+			wantErr: true,
+		},
+		{
+			name:           "transient error leaves the resource out",
+			partialResults: true,
+			listErr:        k8sapierror.NewTooManyRequests("throttled", 1),
+			wantFailures: []ScanFailure{
+				{Resource: "services", Namespace: "app", Error: "throttled"},
+			},
+		},
+		{
+			name:           "other errors fail the listing",
+			partialResults: true,
+			listErr:        k8sapierror.NewBadRequest("invalid"),
+			wantErr:        true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cluster, err := fake.NewCluster(fake.WithYAML(partialClusterYAML))
+			require.NoError(t, err)
+			cluster.DynamicClient.PrependReactor("list", "services", func(k8stesting.Action) (bool, runtime.Object, error) {
+				return true, nil, test.listErr
+			})
+
+			client := New(cluster, WithPartialResults(test.partialResults), WithExcludeKinds([]string{"node"})).
+				Namespace("app").
+				Resources("pods,services")
+			artifactList, err := client.ListArtifacts(context.Background())
+			if test.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			var kinds []string
+			for _, a := range artifactList {
+				kinds = append(kinds, a.Kind)
+			}
+			assert.Contains(t, kinds, "Pod")
+			assert.NotContains(t, kinds, "Service")
+			report := client.PartialResults()
+			assert.Equal(t, test.wantFailures, report.Failures)
+			assert.False(t, report.Complete())
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package trivyk8s
+
+import (
+	"context"
+	"fmt"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/aquasecurity/trivy-kubernetes/pkg/k8s"
+)
+
+// Purposes of the permissions checked by Preflight
+const (
+	PurposeListResources    = "list resources"
+	PurposeImagePullSecrets = "image pull secrets"
+	PurposeNodeConfig       = "node config"
+	PurposeNodeCollector    = "node collector"
+)
+
+// PermissionCheck is a verb checked on a resource for the scanning identity,
+// an empty namespace stands for all namespaces or a cluster resource
+type PermissionCheck struct {
+	Purpose     string `json:"purpose"`
+	Verb        string `json:"verb"`
+	Group       string `json:"group,omitempty"`
+	Resource    string `json:"resource"`
+	Subresource string `json:"subresource,omitempty"`
+	Namespace   string `json:"namespace,omitempty"`
+	Allowed     bool   `json:"allowed"`
+	Reason      string `json:"reason,omitempty"`
+}
+
+// PreflightReport lists the permissions a scan needs and whether they are granted
+type PreflightReport struct {
+	Checks []PermissionCheck `json:"checks"`
+}
+
+// Missing returns the checks which are not allowed
+func (r *PreflightReport) Missing() []PermissionCheck {
+	missing := make([]PermissionCheck, 0)
+	for _, check := range r.Checks {
+		if !check.Allowed {
+			missing = append(missing, check)
+		}
+	}
+	return missing
+}
+
+// MissingByPurpose returns the checks of a purpose which are not allowed
+func (r *PreflightReport) MissingByPurpose(purpose string) []PermissionCheck {
+	missing := make([]PermissionCheck, 0)
+	for _, check := range r.Missing() {
+		if check.Purpose == purpose {
+			missing = append(missing, check)
+		}
+	}
+	return missing
+}
+
+// Preflight reviews, through SelfSubjectAccessReviews, the permissions needed to list
+// the configured resources, read image pull secrets and run the node collector. The
+// node collector is checked with WithNodeCollection when the nodes are listed
+func (c *client) Preflight(ctx context.Context, opts ...NodeCollectorOption) (*PreflightReport, error) {
+	for _, opt := range opts {
+		opt(c)
+	}
+	checks, err := c.preflightChecks()
+	if err != nil {
+		return nil, err
+	}
+	for i := range checks {
+		if err := c.reviewAccess(ctx, &checks[i]); err != nil {
+			return nil, err
+		}
+		if checks[i].Allowed || checks[i].Purpose != PurposeImagePullSecrets {
+			continue
+		}
+		// the credentials are read one by one when they can't be listed
+		get := checks[i]
+		get.Verb = "get"
+		if err := c.reviewAccess(ctx, &get); err != nil {
+			return nil, err
+		}
+		if get.Allowed {
+			checks[i].Allowed = true
+			checks[i].Reason = fmt.Sprintf("list denied, the %s are read one by one with get", checks[i].Resource)
+		}
+	}
+	return &PreflightReport{Checks: checks}, nil
+}
+
+func (c *client) preflightChecks() ([]PermissionCheck, error) {
+	c.initResourceList()
+	gvrs, err := c.cluster.GetGVRs(isNamespaced(c.namespace, c.allNamespaces), c.resources)
+	if err != nil {
+		return nil, err
+	}
+	namespaces := c.includeNamespaces
+	if len(namespaces) == 0 {
+		namespaces = []string{c.namespace}
+	}
+
+	checks := make([]PermissionCheck, 0)
+	add := func(purpose string, gvr schema.GroupVersionResource, subresource, namespace string, verbs ...string) {
+		for _, verb := range verbs {
+			checks = append(checks, PermissionCheck{
+				Purpose:     purpose,
+				Verb:        verb,
+				Group:       gvr.Group,
+				Resource:    gvr.Resource,
+				Subresource: subresource,
+				Namespace:   namespace,
+			})
+		}
+	}
+	collectsNodes := false
+	for _, gvr := range gvrs {
+		if gvr.Resource == k8s.Nodes {
+			collectsNodes = c.nodeCollection
+		}
+		if k8s.IsClusterResource(gvr) {
+			add(PurposeListResources, gvr, "", "", "list", "get")
+			continue
+		}
+		for _, ns := range namespaces {
+			add(PurposeListResources, gvr, "", ns, "list", "get")
+		}
+	}
+	// the service accounts and image pull secrets are listed once per namespace, or
+	// read one by one when the list is denied
+	for _, ns := range namespaces {
+		add(PurposeImagePullSecrets, corev1Resource(k8s.ServiceAccounts), "", ns, "list")
+		add(PurposeImagePullSecrets, corev1Resource("secrets"), "", ns, "list")
+	}
+	if c.nodeConfig {
+		add(PurposeNodeConfig, corev1Resource(k8s.Nodes), "proxy", "", "get")
+	}
+	if collectsNodes {
+		jobNamespace := c.scanJobParams.scanJobNamespace
+		add(PurposeNodeCollector, corev1Resource("namespaces"), "", "", "get", "create")
+		add(PurposeNodeCollector, schema.GroupVersionResource{Group: "batch", Resource: k8s.Jobs}, "", jobNamespace, "create", "get", "delete")
+		add(PurposeNodeCollector, corev1Resource(k8s.Pods), "", jobNamespace, "list")
+		add(PurposeNodeCollector, corev1Resource(k8s.Pods), "log", jobNamespace, "get")
+	}
+	return checks, nil
+}
+
+func (c *client) reviewAccess(ctx context.Context, check *PermissionCheck) error {
+	review := &authorizationv1.SelfSubjectAccessReview{
+		Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace:   check.Namespace,
+				Verb:        check.Verb,
+				Group:       check.Group,
+				Resource:    check.Resource,
+				Subresource: check.Subresource,
+			},
+		},
+	}
+	result, err := c.cluster.GetK8sClient().AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, review, v1.CreateOptions{})
+	if err != nil {
+		return fmt.Errorf("reviewing access to %s %s: %w", check.Verb, check.Resource, err)
+	}
+	check.Allowed = result.Status.Allowed
+	check.Reason = result.Status.Reason
+	return nil
+}
+
+func corev1Resource(resource string) schema.GroupVersionResource {
+	return schema.GroupVersionResource{Resource: resource}
+}
@@ -0,0 +1,122 @@
+package trivyk8s
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/aquasecurity/trivy-kubernetes/pkg/k8s/fake"
+)
+
+func TestPreflight(t *testing.T) {
+	cluster, err := fake.NewCluster()
+	require.NoError(t, err)
+	denied := map[string]bool{"secrets": true, "serviceaccounts": true, "nodes/proxy": true, "jobs": true}
+	cluster.Clientset.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+		attrs := review.Spec.ResourceAttributes
+		resource := attrs.Resource
+		if attrs.Subresource != "" {
+			resource += "/" + attrs.Subresource
+		}
+		review.Status.Allowed = !denied[resource] || resource == "jobs" && attrs.Verb == "get" ||
+			resource == "serviceaccounts" && attrs.Verb == "get"
+		return true, review, nil
+	})
+
+	report, err := New(cluster, WithIncludeKinds([]string{"deployments", "nodes"})).
+		Namespace("app").
+		Preflight(context.Background(), WithNodeConfig(true), WithNodeCollection(true), WithScanJobNamespace("trivy-temp"))
+	require.NoError(t, err)
+
+	assert.Contains(t, report.Checks, PermissionCheck{
+		Purpose:   PurposeListResources,
+		Verb:      "list",
+		Group:     "apps",
+		Resource:  "deployments",
+		Namespace: "app",
+		Allowed:   true,
+	})
+	assert.Contains(t, report.Checks, PermissionCheck{
+		Purpose:  PurposeListResources,
+		Verb:     "list",
+		Resource: "nodes",
+		Allowed:  true,
+	})
+	assert.Equal(t, []PermissionCheck{
+		{Purpose: PurposeImagePullSecrets, Verb: "list", Resource: "secrets", Namespace: "app"},
+	}, report.MissingByPurpose(PurposeImagePullSecrets))
+	assert.Contains(t, report.Checks, PermissionCheck{
+		Purpose:   PurposeImagePullSecrets,
+		Verb:      "list",
+		Resource:  "serviceaccounts",
+		Namespace: "app",
+		Allowed:   true,
+		Reason:    "list denied, the serviceaccounts are read one by one with get",
+	}, "get is enough to read the service accounts")
+	assert.Equal(t, []PermissionCheck{
+		{Purpose: PurposeNodeConfig, Verb: "get", Resource: "nodes", Subresource: "proxy"},
+	}, report.MissingByPurpose(PurposeNodeConfig))
+	assert.Equal(t, []PermissionCheck{
+		{Purpose: PurposeNodeCollector, Verb: "create", Group: "batch", Resource: "jobs", Namespace: "trivy-temp"},
+		{Purpose: PurposeNodeCollector, Verb: "delete", Group: "batch", Resource: "jobs", Namespace: "trivy-temp"},
+	}, report.MissingByPurpose(PurposeNodeCollector))
+	assert.Len(t, report.Missing(), 4)
+}
+
+func TestPreflightNodeCollector(t *testing.T) {
+	tests := []struct {
+		name         string
+		includeKinds []string
+		opts         []NodeCollectorOption
+		want         bool
+	}{
+		{
+			name:         "node collection",
+			includeKinds: []string{"pods", "nodes"},
+			opts:         []NodeCollectorOption{WithNodeCollection(true)},
+			want:         true,
+		},
+		{
+			name:         "no node collection",
+			includeKinds: []string{"pods", "nodes"},
+		},
+		{
+			name:         "unrelated node collector options",
+			includeKinds: []string{"pods", "nodes"},
+			opts:         []NodeCollectorOption{WithScanJobNamespace("trivy-temp"), WithNodeConfig(true)},
+		},
+		{
+			name:         "nodes not listed",
+			includeKinds: []string{"pods"},
+			opts:         []NodeCollectorOption{WithNodeCollection(true)},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cluster, err := fake.NewCluster()
+			require.NoError(t, err)
+			cluster.Clientset.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+				return true, review, nil
+			})
+
+			report, err := New(cluster, WithIncludeKinds(test.includeKinds)).
+				Namespace("app").
+				Preflight(context.Background(), test.opts...)
+			require.NoError(t, err)
+
+			var collector bool
+			for _, check := range report.Checks {
+				collector = collector || check.Purpose == PurposeNodeCollector
+			}
+			assert.Equal(t, test.want, collector)
+		})
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"log/slog"
 	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/aquasecurity/trivy-kubernetes/pkg/artifacts"
@@ -43,6 +44,10 @@ type ArtifactsK8S interface {
 	ListArtifactAndNodeInfo(context.Context, ...NodeCollectorOption) ([]*artifacts.Artifact, error)
 	// ListClusterBomInfo returns kubernetes Bom (node,core components) information.
 	ListClusterBomInfo(context.Context) ([]*artifacts.Artifact, error)
+	// Preflight reports the permissions missing to list artifacts and collect node info
+	Preflight(context.Context, ...NodeCollectorOption) (*PreflightReport, error)
+	// PartialResults reports the retries of the last listing and what it left out
+	PartialResults() *PartialResults
 }
 
 type client struct {
@@ -51,8 +56,23 @@ type client struct {
 	resources            []string
 	allNamespaces        bool
 	excludeOwned         bool
+	networkExposure      bool
+	networkPolicies      bool
+	gitOpsSources        bool
+	configReferences     bool
+	referencedConfigOnly bool
+	imageHygiene         bool
+	allowedRegistries    []string
+	clusterIdentity      bool
+	versionSkew          bool
+	releaseCalendar      *artifacts.ReleaseCalendar
+	componentCatalog     *k8s.ComponentCatalog
+	partialResults       bool
+	report               *PartialResults
+	reportMu             sync.Mutex
 	scanJobParams        scanJobParams
 	nodeConfig           bool // feature flag to enable/disable node config collection
+	nodeCollection       bool
 	excludeKinds         []string
 	includeKinds         []string
 	excludeNamespaces    []string
@@ -65,11 +85,115 @@ type client struct {
 
 type K8sOption func(*client)
 
+// Argo CD and Flux resources describing where GitOps managed objects come from
+var gitOpsResources = []string{
+	"applications.argoproj.io",
+	"kustomizations.kustomize.toolkit.fluxcd.io",
+	"helmreleases.helm.toolkit.fluxcd.io",
+	"gitrepositories.source.toolkit.fluxcd.io",
+	"helmrepositories.source.toolkit.fluxcd.io",
+	"ocirepositories.source.toolkit.fluxcd.io",
+}
+
 func WithExcludeOwned(excludeOwned bool) K8sOption {
 	return func(c *client) {
 		c.excludeOwned = excludeOwned
 	}
 }
+
+// WithNetworkExposure classifies the network exposure of listed workloads
+func WithNetworkExposure(networkExposure bool) K8sOption {
+	return func(c *client) {
+		c.networkExposure = networkExposure
+	}
+}
+
+// WithNetworkPolicyCoverage relates listed workloads to the NetworkPolicies selecting them
+func WithNetworkPolicyCoverage(networkPolicies bool) K8sOption {
+	return func(c *client) {
+		c.networkPolicies = networkPolicies
+	}
+}
+
+// WithGitOpsSources attributes listed artifacts to the Argo CD or Flux objects managing them
+func WithGitOpsSources(gitOpsSources bool) K8sOption {
+	return func(c *client) {
+		c.gitOpsSources = gitOpsSources
+	}
+}
+
+// WithConfigReferences maps the ConfigMaps and Secrets referenced by listed workloads
+// and flags references to missing objects
+func WithConfigReferences(configReferences bool) K8sOption {
+	return func(c *client) {
+		c.configReferences = configReferences
+	}
+}
+
+// WithReferencedConfigOnly skips ConfigMaps and Secrets which are not referenced by
+// any listed workload
+func WithReferencedConfigOnly(referencedConfigOnly bool) K8sOption {
+	return func(c *client) {
+		c.referencedConfigOnly = referencedConfigOnly
+	}
+}
+
+// WithImageHygiene flags tag, digest, registry and pull policy issues of the images
+// of listed workloads
+func WithImageHygiene(imageHygiene bool) K8sOption {
+	return func(c *client) {
+		c.imageHygiene = imageHygiene
+	}
+}
+
+// WithAllowedRegistries flags workload images pulled from registries outside the
+// allow-list, it enables image hygiene analysis
+func WithAllowedRegistries(allowedRegistries []string) K8sOption {
+	return func(c *client) {
+		c.allowedRegistries = allowedRegistries
+	}
+}
+
+// WithClusterIdentity tags listed artifacts with the cluster ID and current context
+func WithClusterIdentity(clusterIdentity bool) K8sOption {
+	return func(c *client) {
+		c.clusterIdentity = clusterIdentity
+	}
+}
+
+// WithVersionSkew evaluates the kubelet and kube-proxy version skew and the support
+// status of the cluster BOM artifacts
+func WithVersionSkew(versionSkew bool) K8sOption {
+	return func(c *client) {
+		c.versionSkew = versionSkew
+	}
+}
+
+// WithReleaseCalendar replaces the embedded release calendar of the version skew
+// analysis, it enables the analysis
+func WithReleaseCalendar(calendar *artifacts.ReleaseCalendar) K8sOption {
+	return func(c *client) {
+		c.releaseCalendar = calendar
+	}
+}
+
+// WithComponentCatalog replaces the embedded catalog detecting the BOM components
+// of the cluster
+func WithComponentCatalog(catalog *k8s.ComponentCatalog) K8sOption {
+	return func(c *client) {
+		c.componentCatalog = catalog
+	}
+}
+
+// WithPartialResults leaves out the resources failing with a transient error once
+// their retries are exhausted, and the nodes whose collection fails, instead of
+// failing the listing. PartialResults reports what was left out
+func WithPartialResults(partialResults bool) K8sOption {
+	return func(c *client) {
+		c.partialResults = partialResults
+	}
+}
+
 func WithExcludeKinds(excludeKinds []string) K8sOption {
 	return func(c *client) {
 		for _, kind := range excludeKinds {
@@ -111,6 +235,11 @@ func New(cluster k8s.Cluster, opts ...K8sOption) TrivyK8S {
 	return c
 }
 
+// bomOptions returns the options of the BOM collection
+func (c *client) bomOptions() []k8s.BomOption {
+	return []k8s.BomOption{k8s.WithComponentCatalog(c.componentCatalog)}
+}
+
 // Namespace configure the namespace to execute the queries
 func (c *client) Namespace(namespace string) TrivyK8S {
 	c.namespace = namespace
@@ -157,7 +286,7 @@ func (c *client) GetExcludeKinds() []string {
 }
 
 // return list of kinds to include
-func (c client) GetIncludeKinds() []string {
+func (c *client) GetIncludeKinds() []string {
 	return c.includeKinds
 }
 
@@ -222,13 +351,21 @@ func (c *client) getNamespaces() ([]string, error) {
 
 // ListArtifacts returns kubernetes scannable artifacs.
 func (c *client) ListArtifacts(ctx context.Context) ([]*artifacts.Artifact, error) {
+	return c.listArtifacts(c.startReport(ctx))
+}
+
+func (c *client) listArtifacts(ctx context.Context) ([]*artifacts.Artifact, error) {
 	c.initResourceList()
 	namespaces, err := c.getNamespaces()
 	if err != nil {
 		return nil, err
 	}
 	if len(namespaces) == 0 {
-		return c.ListSpecificArtifacts(ctx)
+		artifactList, err := c.ListSpecificArtifacts(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return c.analyzeArtifacts(ctx, artifactList)
 	}
 	artifactList := make([]*artifacts.Artifact, 0)
 
@@ -240,9 +377,142 @@ func (c *client) ListArtifacts(ctx context.Context) ([]*artifacts.Artifact, erro
 		}
 		artifactList = append(artifactList, arts...)
 	}
+	return c.analyzeArtifacts(ctx, artifactList)
+}
+
+// analyzeArtifacts runs the enabled analyses over the listed artifacts
+func (c *client) analyzeArtifacts(ctx context.Context, artifactList []*artifacts.Artifact) ([]*artifacts.Artifact, error) {
+	artifacts.MapHelmReleases(artifactList)
+	if c.networkExposure {
+		artifacts.ClassifyExposure(artifactList)
+	}
+	if c.networkPolicies {
+		namespaceLabels, err := c.listNamespaceLabels(ctx)
+		if err != nil {
+			return nil, err
+		}
+		artifacts.AnalyzeNetworkPolicies(artifactList, namespaceLabels)
+	}
+	if c.gitOpsSources {
+		owners, err := c.listGitOpsOwners(ctx)
+		if err != nil {
+			return nil, err
+		}
+		artifacts.AttributeGitOpsSources(artifactList, owners)
+	}
+	if c.configReferences || c.referencedConfigOnly {
+		inventory, err := c.listConfigInventory(ctx, artifactList)
+		if err != nil {
+			return nil, err
+		}
+		artifacts.AnalyzeConfigReferences(artifactList, inventory)
+		for workload, refs := range artifacts.DanglingConfigReferences(artifactList) {
+			for _, ref := range refs {
+				slog.Warn("Workload references a missing object", "workload", workload, "kind", ref.Kind, "name", ref.Name)
+			}
+		}
+	}
+	if c.referencedConfigOnly {
+		artifactList = artifacts.FilterReferencedConfig(artifactList)
+	}
+	if c.imageHygiene || len(c.allowedRegistries) > 0 {
+		artifacts.AnalyzeImageHygiene(artifactList, c.allowedRegistries)
+	}
+	if c.clusterIdentity {
+		id, err := c.cluster.GetClusterID(ctx)
+		if err != nil {
+			return nil, err
+		}
+		identity := &artifacts.ClusterIdentity{ID: id, Context: c.cluster.GetCurrentContext()}
+		for _, a := range artifactList {
+			a.Cluster = identity
+		}
+	}
 	return artifactList, nil
 }
 
+// listConfigInventory lists the metadata of the ConfigMaps and Secrets of the
+// workload namespaces, a kind which can't be listed is left out of the inventory
+func (c *client) listConfigInventory(ctx context.Context, artifactList []*artifacts.Artifact) (artifacts.ConfigInventory, error) {
+	namespaces := make([]string, 0)
+	for _, a := range artifactList {
+		if a.IsWorkload() && !slices.Contains(namespaces, a.Namespace) {
+			namespaces = append(namespaces, a.Namespace)
+		}
+	}
+	inventory := make(artifacts.ConfigInventory)
+	// only the names are needed, the secret values are not read
+	metadataClient := c.cluster.GetMetadataClient()
+	if metadataClient == nil {
+		slog.Warn("Unable to list config objects without a metadata client")
+		return inventory, nil
+	}
+	for _, kind := range []string{artifacts.KindConfigMap, artifacts.KindSecret} {
+		gvr := schema.GroupVersionResource{Version: "v1", Resource: strings.ToLower(kind) + "s"}
+		refs := make([]artifacts.ObjectRef, 0)
+		complete := true
+		for _, namespace := range namespaces {
+			objects, err := metadataClient.Resource(gvr).Namespace(namespace).List(ctx, v1.ListOptions{})
+			if err != nil {
+				if errors.IsNotFound(err) || errors.IsForbidden(err) {
+					slog.Error("Unable to list config objects", "error", fmt.Errorf("gvr: %v - %w", gvr, err))
+					complete = false
+					break
+				}
+				return nil, fmt.Errorf("failed listing config objects for gvr: %v - %w", gvr, err)
+			}
+			for _, o := range objects.Items {
+				refs = append(refs, artifacts.ObjectRef{Kind: kind, Namespace: o.GetNamespace(), Name: o.GetName()})
+			}
+		}
+		if complete {
+			inventory[kind] = refs
+		}
+	}
+	return inventory, nil
+}
+
+// listNamespaceLabels lists the labels of the namespaces the NetworkPolicy
+// namespaceSelectors are evaluated against, none when they can't be listed
+func (c *client) listNamespaceLabels(ctx context.Context) (artifacts.NamespaceLabels, error) {
+	namespaceGVR := schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}
+	namespaces, err := c.cluster.GetDynamicClient().Resource(namespaceGVR).List(ctx, v1.ListOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) || errors.IsForbidden(err) {
+			slog.Warn("Unable to list namespace labels", "error", err)
+			return nil, nil
+		}
+		return nil, fmt.Errorf("unable to list namespaces: %w", err)
+	}
+	namespaceLabels := make(artifacts.NamespaceLabels, len(namespaces.Items))
+	for _, ns := range namespaces.Items {
+		namespaceLabels[ns.GetName()] = ns.GetLabels()
+	}
+	return namespaceLabels, nil
+}
+
+// listGitOpsOwners lists the Argo CD and Flux resources installed in the cluster
+func (c *client) listGitOpsOwners(ctx context.Context) ([]unstructured.Unstructured, error) {
+	owners := make([]unstructured.Unstructured, 0)
+	for _, resource := range gitOpsResources {
+		gvr, err := c.cluster.GetGVR(resource)
+		if err != nil {
+			// GitOps tool is not installed
+			continue
+		}
+		resources, err := c.cluster.GetDynamicClient().Resource(gvr).List(ctx, v1.ListOptions{})
+		if err != nil {
+			if errors.IsNotFound(err) || errors.IsForbidden(err) {
+				slog.Error("Unable to list GitOps resources", "error", fmt.Errorf("gvr: %v - %w", gvr, err))
+				continue
+			}
+			return nil, fmt.Errorf("failed listing GitOps resources for gvr: %v - %w", gvr, err)
+		}
+		owners = append(owners, resources.Items...)
+	}
+	return owners, nil
+}
+
 // ListSpecificArtifacts returns kubernetes scannable artifacs for a specific namespace or a cluster
 func (c *client) ListSpecificArtifacts(ctx context.Context) ([]*artifacts.Artifact, error) {
 	artifactList := make([]*artifacts.Artifact, 0)
@@ -253,6 +523,8 @@ func (c *client) ListSpecificArtifacts(ctx context.Context) ([]*artifacts.Artifa
 		return nil, err
 	}
 
+	// the credentials are read once per namespace of the listing
+	credentials := k8s.NewCredentialCache()
 	for _, gvr := range grvs {
 		dclient := c.getDynamicClient(gvr)
 
@@ -264,6 +536,9 @@ func (c *client) ListSpecificArtifacts(ctx context.Context) ([]*artifacts.Artifa
 				slog.Error("Unable to list resources", "error", lerr)
 				continue
 			}
+			if k8s.IsTransientError(err) && c.leaveOut(ScanFailure{Resource: gvr.Resource, Namespace: c.namespace, Error: err.Error()}) {
+				continue
+			}
 
 			return nil, lerr
 		}
@@ -278,7 +553,7 @@ func (c *client) ListSpecificArtifacts(ctx context.Context) ([]*artifacts.Artifa
 				continue
 			}
 
-			auths, err := c.cluster.AuthByResource(resource)
+			auths, err := c.cluster.AuthByResource(resource, k8s.WithCredentialCache(credentials))
 			if err != nil {
 				return nil, fmt.Errorf("failed getting auth for gvr: %v - %w", gvr, err)
 			}
@@ -297,7 +572,7 @@ func (c *client) ListSpecificArtifacts(ctx context.Context) ([]*artifacts.Artifa
 		}
 		return append(artifactList, bomArtifacts...), nil
 	}
-	bomComponents, err := c.cluster.CreateBomComponents(ctx, c.namespace)
+	bomComponents, err := c.cluster.CreateBomComponents(ctx, c.namespace, c.bomOptions()...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get BOM artifacts: %w", err)
 	}
@@ -372,6 +647,15 @@ func WithNodeConfig(nodeConfig bool) NodeCollectorOption {
 		c.nodeConfig = nodeConfig
 	}
 }
+
+// WithNodeCollection makes Preflight check the permissions of the node collector
+// run by ListArtifactAndNodeInfo
+func WithNodeCollection(nodeCollection bool) NodeCollectorOption {
+	return func(c *client) {
+		c.nodeCollection = nodeCollection
+	}
+}
+
 func WithCommandPaths(commandPaths []string) NodeCollectorOption {
 	return func(c *client) {
 		c.commandPaths = commandPaths
@@ -401,7 +685,8 @@ func (c *client) ListArtifactAndNodeInfo(ctx context.Context,
 	for _, opt := range opts {
 		opt(c)
 	}
-	artifactList, err := c.ListArtifacts(ctx)
+	ctx = c.startReport(ctx)
+	artifactList, err := c.listArtifacts(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -445,6 +730,9 @@ func (c *client) ListArtifactAndNodeInfo(ctx context.Context,
 		jc.AppendLabels(jobs.WithJobLabels(nodeLabels))
 		output, err := jc.ApplyAndCollect(ctx, resource.Name)
 		if err != nil {
+			if c.leaveOut(ScanFailure{Resource: k8s.Nodes, Name: resource.Name, Error: err.Error()}) {
+				continue
+			}
 			return nil, err
 		}
 		var nodeInfo map[string]interface{}
@@ -463,7 +751,7 @@ func (c *client) ListArtifactAndNodeInfo(ctx context.Context,
 
 // ListClusterBomInfo returns kubernetes Bom (node,core components and etc) information.
 func (c *client) ListClusterBomInfo(ctx context.Context) ([]*artifacts.Artifact, error) {
-	b, err := c.cluster.CreateClusterBom(ctx)
+	b, err := c.cluster.CreateClusterBom(ctx, c.bomOptions()...)
 	if err != nil {
 		return []*artifacts.Artifact{}, err
 	}
@@ -471,7 +759,20 @@ func (c *client) ListClusterBomInfo(ctx context.Context) ([]*artifacts.Artifact,
 	if slices.Contains(c.GetExcludeKinds(), "node") {
 		b.NodesInfo = []bom.NodeInfo{}
 	}
-	return BomToArtifacts(b)
+	// the graph references the filtered out components and nodes
+	b.Dependencies = bom.BuildDependencies(b)
+	artifactList, err := BomToArtifacts(b)
+	if err != nil {
+		return nil, err
+	}
+	if c.versionSkew || c.releaseCalendar != nil {
+		calendar := c.releaseCalendar
+		if calendar == nil {
+			calendar = artifacts.DefaultReleaseCalendar()
+		}
+		artifacts.AnalyzeVersionSkew(artifactList, calendar, time.Now())
+	}
+	return artifactList, nil
 }
 
 func (c *client) filterNamespaces(comp []bom.Component) []bom.Component {
@@ -492,10 +793,14 @@ func convertBomComponentsToToArtifacts(components []bom.Component) ([]*artifacts
 		if err != nil {
 			return []*artifacts.Artifact{}, err
 		}
+		kind, name := "ControlPlaneComponents", c.Name
+		if k8s.IsHelmRelease(c) {
+			kind, name = artifacts.KindHelmChartRelease, c.Properties["Name"]
+		}
 		artifactList = append(artifactList, &artifacts.Artifact{
-			Kind:        "ControlPlaneComponents",
+			Kind:        kind,
 			Namespace:   c.Namespace,
-			Name:        c.Name,
+			Name:        name,
 			RawResource: rawResource,
 		})
 	}
@@ -513,22 +818,23 @@ func BomToArtifacts(b *bom.Result) ([]*artifacts.Artifact, error) {
 			return []*artifacts.Artifact{}, err
 		}
 		artifactList = append(artifactList, &artifacts.Artifact{
-			Kind:        "NodeComponents",
+			Kind:        artifacts.KindNodeComponents,
 			Name:        ni.NodeName,
 			RawResource: rawResource,
 		})
 	}
 	cr, err := rawResource(&bom.Result{
-		ID:         b.ID,
-		Type:       "ClusterInfo",
-		Version:    b.Version,
-		Properties: b.Properties,
+		ID:           b.ID,
+		Type:         "ClusterInfo",
+		Version:      b.Version,
+		Properties:   b.Properties,
+		Dependencies: b.Dependencies,
 	})
 	if err != nil {
 		return []*artifacts.Artifact{}, err
 	}
 	artifactList = append(artifactList, &artifacts.Artifact{
-		Kind:        "Cluster",
+		Kind:        artifacts.KindCluster,
 		Name:        b.ID,
 		RawResource: cr,
 	})
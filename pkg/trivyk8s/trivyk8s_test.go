@@ -14,8 +14,10 @@ import (
 	"time"
 
 	"github.com/aquasecurity/trivy-kubernetes/pkg/artifacts"
+	"github.com/aquasecurity/trivy-kubernetes/pkg/bom"
 	"github.com/aquasecurity/trivy-kubernetes/pkg/k8s"
 	"github.com/aquasecurity/trivy-kubernetes/pkg/k8s/docker"
+	"github.com/aquasecurity/trivy-kubernetes/pkg/k8s/fake"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/testcontainers/testcontainers-go"
@@ -153,6 +155,139 @@ func TestInitResources(t *testing.T) {
 
 type kubectlAction func() error
 
+const bomClusterYAML = `
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: kube-system
+  uid: 9a4c3b1e-5d2f-4e8a-b7c6-1f0e2d3c4b5a
+---
+apiVersion: v1
+kind: Node
+metadata:
+  name: node-1
+status:
+  nodeInfo:
+    kubeletVersion: v1.31.0
+    containerRuntimeVersion: containerd://1.7.20
+    osImage: Ubuntu 24.04 LTS
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: cilium-x2v9k
+  namespace: kube-system
+  labels:
+    k8s-app: cilium
+spec:
+  nodeName: node-1
+  containers:
+  - name: cilium-agent
+    image: quay.io/cilium/cilium:v1.15.6
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: calico-node-4wq8d
+  namespace: calico-system
+spec:
+  nodeName: node-1
+  containers:
+  - name: calico-node
+    image: docker.io/calico/node:v3.28.0
+`
+
+func TestListClusterBomInfo(t *testing.T) {
+	cluster, err := fake.NewCluster(fake.WithYAML(bomClusterYAML))
+	require.NoError(t, err)
+
+	artifactList, err := New(cluster,
+		WithExcludeNamespaces([]string{"calico-system"}),
+		WithExcludeKinds([]string{"node"}),
+	).ListClusterBomInfo(context.Background())
+	require.NoError(t, err)
+
+	var refs []string
+	for _, a := range artifactList {
+		assert.NotEqual(t, artifacts.KindNodeComponents, a.Kind)
+		if a.Kind != artifacts.KindCluster {
+			continue
+		}
+		data, err := json.Marshal(a.RawResource["dependencies"])
+		require.NoError(t, err)
+		var dependencies []bom.Dependency
+		require.NoError(t, json.Unmarshal(data, &dependencies))
+		for _, d := range dependencies {
+			refs = append(refs, d.Ref)
+			refs = append(refs, d.DependsOn...)
+		}
+	}
+	require.NotEmpty(t, refs)
+	assert.Contains(t, refs, "component:kube-system/cilium-x2v9k")
+	for _, ref := range refs {
+		assert.NotContains(t, ref, "calico", "excluded namespace")
+		assert.NotContains(t, ref, bom.NodeRefPrefix, "excluded nodes")
+	}
+}
+
+func TestConfigInventory(t *testing.T) {
+	cluster, err := fake.NewCluster(fake.WithYAML(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: api
+  namespace: app
+spec:
+  template:
+    spec:
+      containers:
+        - name: api
+          image: ghcr.io/acme/api:1.0
+          env:
+            - name: PASSWORD
+              valueFrom:
+                secretKeyRef:
+                  name: db
+                  key: password
+            - name: FEATURES
+              valueFrom:
+                configMapKeyRef:
+                  name: features
+                  key: flags
+                  optional: true
+      volumes:
+        - name: tls
+          secret:
+            secretName: tls
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: db
+  namespace: app
+data:
+  password: c2VjcmV0
+`))
+	require.NoError(t, err)
+
+	artifactList, err := New(cluster, WithIncludeKinds([]string{"deployments"}), WithConfigReferences(true)).
+		Namespace("app").
+		ListArtifacts(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, map[artifacts.ObjectRef][]artifacts.ConfigReference{
+		{Kind: "Deployment", Namespace: "app", Name: "api"}: {{
+			ObjectRef: artifacts.ObjectRef{Kind: artifacts.KindSecret, Namespace: "app", Name: "tls"},
+			Source:    artifacts.RefSourceVolume,
+			Missing:   true,
+			Dangling:  true,
+		}},
+	}, artifacts.DanglingConfigReferences(artifactList))
+	for _, action := range cluster.DynamicClient.Actions() {
+		assert.NotEqual(t, "secrets", action.GetResource().Resource, "the secret values are not read")
+	}
+}
+
 func TestListArtifacts(t *testing.T) {
 	const nodeHashName = "node-af4de95017af"
 